@@ -3,14 +3,16 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
 	"time"
 
 	"github.com/edgeDetection/edgedetection"
+	"github.com/edgeDetection/export"
 	"github.com/edgeDetection/hdbscan"
 	"github.com/fatih/color"
 )
@@ -22,14 +24,23 @@ const (
 type Files struct {
 	Mesh  string `json:"objfile"`
 	Depth string `json:"depthfile"`
-	Img   string `json:"texture"`
+	// DepthHeader is an optional JSON sidecar with the depth format
+	DepthHeader string `json:"depthheader"`
+	Img         string `json:"texture"`
 }
 
 func main() {
 
-	if len(os.Args) > 1 {
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed for all random decisions")
+	format := flag.String("export", "", "additionally write all clusters to one file: ply, plyascii, las, csv, columnar or geojson")
+	debugDir := flag.String("debug", "", "write the intermediate images as PNG files to this directory")
+	show := flag.Bool("show", false, "show the intermediate images in windows")
+	configFile := flag.String("config", "", "JSON or YAML file with the detection parameters")
+	flag.Parse()
 
-		argument := os.Args[1]
+	if flag.NArg() > 0 {
+
+		argument := flag.Arg(0)
 		fmt.Println(argument)
 		jsonReader, err := os.Open(Filenames)
 		if err != nil {
@@ -49,37 +60,98 @@ func main() {
 		}
 
 		meshReader, _ := os.Open(argument + files.Mesh)
-		depthReader, _ := os.Open(argument + files.Depth)
-		imgReader, _ := os.Open(argument + files.Img)
-
 		defer meshReader.Close()
-		defer depthReader.Close()
-		defer imgReader.Close()
 
-		detections := edgedetection.Detection(meshReader, depthReader, imgReader)
-		log.Println("Number of points to cluster: ", len(detections.Normale))
+		loader, err := edgedetection.LoaderForFile(files.Mesh)
+		if err != nil {
+			panic(err)
+		}
+		mesh, err := loader.Load(meshReader)
+		if err != nil {
+			panic(err)
+		}
+
+		depth, err := readDepth(argument, files)
+		if err != nil {
+			panic(err)
+		}
+
+		// glTF files can embed the texture
+		var imgReader io.Reader
+		if files.Img != "" {
+			imgFile, _ := os.Open(argument + files.Img)
+			defer imgFile.Close()
+			imgReader = imgFile
+		}
+
+		config, err := readConfig(*configFile)
+		if err != nil {
+			panic(err)
+		}
+		if config.Seed == nil {
+			config.Seed = seed
+		}
+		switch {
+		case *show:
+			config.Debug = edgedetection.WindowSink{}
+		case *debugDir != "":
+			config.Debug, err = edgedetection.NewDirSink(*debugDir)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		detections, err := edgedetection.DetectionMesh(mesh, depth, imgReader, config)
+		if err != nil {
+			panic(err)
+		}
+		log.Println("Number of planes: ", len(detections.Planes))
+		log.Println("Number of points to cluster: ", len(detections.Normals()))
 
 		// hdbscan
 		minimumClusterSize := 500
 		minimumSpanningTree := true
 
-		clustering, err := hdbscan.NewClustering(detections.Normale, minimumClusterSize, argument)
+		clustering, err := hdbscan.NewClustering(detections.Normals(), minimumClusterSize, argument)
 		if err != nil {
 			panic(err)
 		}
 
 		// Set options for clustering
-		clustering = clustering.Verbose().OutlierDetection().NearestNeighbor()
+		clustering = clustering.Verbose().OutlierDetection().NearestNeighbor().Seed(*seed)
 		clustering.Run(hdbscan.AngleVector, hdbscan.StabilityScore, minimumSpanningTree)
 
 		writeClusterToObj(clustering, detections, argument)
+
+		err = writeLabelledMesh(clustering, detections, argument)
+		if err != nil {
+			color.Red("Cannot write labelled mesh:", err)
+		}
+
+		err = writeClusterImages(clustering, detections, argument)
+		if err != nil {
+			color.Red("Cannot write cluster images:", err)
+		}
+
+		err = writeClusterShapes(clustering, detections, argument)
+		if err != nil {
+			color.Red("Cannot write cluster shapes:", err)
+		}
+
+		if *format != "" {
+			err = exportClusters(clustering, detections, argument, *format)
+			if err != nil {
+				color.Red("Cannot export clusters:", err)
+			}
+		}
 	} else {
 		panic("No file founded!")
 	}
 }
 
 func writeClusterToObj(c *hdbscan.Clustering, d *edgedetection.Data, argument string) {
-	colors, _ := getcolors(len(c.Clusters))
+	colors, _ := edgedetection.PaletteColors(len(c.Clusters))
+	samples := d.Samples()
 	for i, cl := range c.Clusters {
 		outputfile, _ := os.Create(argument + "cluster_" + fmt.Sprint(i) + "_.obj")
 		defer outputfile.Close()
@@ -87,9 +159,9 @@ func writeClusterToObj(c *hdbscan.Clustering, d *edgedetection.Data, argument st
 
 		for _, p := range cl.Points {
 
-			x := fmt.Sprintf("%f", d.Barycenter[p][0])
-			y := fmt.Sprintf("%f", d.Barycenter[p][1])
-			z := fmt.Sprintf("%f", d.Barycenter[p][2])
+			x := fmt.Sprintf("%f", samples[p][0])
+			y := fmt.Sprintf("%f", samples[p][1])
+			z := fmt.Sprintf("%f", samples[p][2])
 
 			c := colors[i]
 			R := fmt.Sprintf("%1.3f", c.R)
@@ -103,9 +175,9 @@ func writeClusterToObj(c *hdbscan.Clustering, d *edgedetection.Data, argument st
 		}
 		for _, p := range cl.Outliers {
 
-			x := fmt.Sprintf("%f", d.Barycenter[p.Index][0])
-			y := fmt.Sprintf("%f", d.Barycenter[p.Index][1])
-			z := fmt.Sprintf("%f", d.Barycenter[p.Index][2])
+			x := fmt.Sprintf("%f", samples[p.Index][0])
+			y := fmt.Sprintf("%f", samples[p.Index][1])
+			z := fmt.Sprintf("%f", samples[p.Index][2])
 
 			// c := colors[i]
 			R := fmt.Sprintf("%1.3f", 0.502)
@@ -122,19 +194,179 @@ func writeClusterToObj(c *hdbscan.Clustering, d *edgedetection.Data, argument st
 	}
 }
 
-func getcolors(k int) ([]edgedetection.Color, []string) {
-	time := time.Now().Nanosecond()
-	rand.Seed(int64(time))
+func exportClusters(c *hdbscan.Clustering, d *edgedetection.Data, argument, format string) error {
+	extension := map[string]string{
+		"ply":      ".ply",
+		"plyascii": ".ply",
+		"las":      ".las",
+		"csv":      ".csv",
+		"columnar": ".hdbc",
+		"geojson":  ".geojson",
+	}[format]
+	if extension == "" {
+		return fmt.Errorf("unknown export format %q", format)
+	}
 
-	colors := make([]edgedetection.Color, k)
-	colorname := make([]string, k)
-	for i := 0; i < k; i++ {
+	outputfile, err := os.Create(argument + "clusters" + extension)
+	if err != nil {
+		return err
+	}
+	defer outputfile.Close()
 
-		randnum := rand.Intn(len(edgedetection.Colorsrand)) // colorsrand[randnum]
-		color := edgedetection.Colorsrand[randnum]
+	samples := d.Samples()
+	switch format {
+	case "ply":
+		err = export.WritePLY(outputfile, c, samples, true)
+	case "plyascii":
+		err = export.WritePLY(outputfile, c, samples, false)
+	case "las":
+		err = export.WriteLAS(outputfile, c, samples, 0.001)
+	case "csv":
+		err = export.WriteCSV(outputfile, c, samples)
+	case "columnar":
+		err = export.WriteColumnar(outputfile, c, samples)
+	case "geojson":
+		err = export.WriteGeoJSON(outputfile, c, samples)
+	}
+	if err != nil {
+		return err
+	}
+
+	return outputfile.Close()
+}
 
-		colors[i], _ = edgedetection.IsColorName(color)
-		colorname[i] = color
+func writeLabelledMesh(c *hdbscan.Clustering, d *edgedetection.Data, argument string) error {
+	objFile, err := os.Create(argument + "clustered_mesh.obj")
+	if err != nil {
+		return err
 	}
-	return colors, colorname
+	defer objFile.Close()
+
+	mtlFile, err := os.Create(argument + "clustered_mesh.mtl")
+	if err != nil {
+		return err
+	}
+	defer mtlFile.Close()
+
+	err = export.WriteLabelledOBJ(objFile, mtlFile, "clustered_mesh.mtl", d, c)
+	if err != nil {
+		return err
+	}
+
+	err = mtlFile.Close()
+	if err != nil {
+		return err
+	}
+	return objFile.Close()
+}
+
+// writeClusterImages writes the footprints of the clusters in the texture:
+// an overlay of all clusters, an overlay and a mask per cluster and
+// the bounding boxes and hulls as JSON.
+func writeClusterImages(c *hdbscan.Clustering, d *edgedetection.Data, argument string) error {
+	footprints, err := export.Footprints(d, c)
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(argument+"clusters_overlay.png", func(w io.Writer) error {
+		return export.WriteOverlayPNG(w, d, footprints)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(argument+"clusters.json", func(w io.Writer) error {
+		return export.WriteFootprintsJSON(w, d, footprints)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, f := range footprints {
+		name := argument + "cluster_" + fmt.Sprint(f.Label)
+		err = writeFile(name+"_overlay.png", func(w io.Writer) error {
+			return export.WriteOverlayPNG(w, d, footprints[i:i+1])
+		})
+		if err != nil {
+			return err
+		}
+		err = writeFile(name+"_mask.png", func(w io.Writer) error {
+			return export.WriteMaskPNG(w, d, f)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeClusterShapes writes the bounding boxes, hulls and shape
+// features of the clusters as JSON.
+func writeClusterShapes(c *hdbscan.Clustering, d *edgedetection.Data, argument string) error {
+	shapes, err := export.ClusterShapes(d, c)
+	if err != nil {
+		return err
+	}
+	return writeFile(argument+"cluster_shapes.json", func(w io.Writer) error {
+		return export.WriteShapesJSON(w, shapes)
+	})
+}
+
+func writeFile(name string, write func(io.Writer) error) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = write(file)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// readDepth reads the depth file with the format of the optional sidecar header.
+// It returns nil if no depth file is given.
+func readDepth(argument string, files Files) (*edgedetection.DepthMap, error) {
+	if files.Depth == "" {
+		return nil, nil
+	}
+
+	var format edgedetection.DepthFormat
+	if files.DepthHeader != "" {
+		headerReader, err := os.Open(argument + files.DepthHeader)
+		if err != nil {
+			return nil, err
+		}
+		defer headerReader.Close()
+
+		format, err = edgedetection.ReadDepthHeader(headerReader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	depthReader, err := os.Open(argument + files.Depth)
+	if err != nil {
+		return nil, err
+	}
+	defer depthReader.Close()
+
+	return edgedetection.ReadDepthFile(depthReader, format)
+}
+
+// readConfig reads the detection parameters, the defaults are used without a file.
+func readConfig(filename string) (*edgedetection.DetectionConfig, error) {
+	if filename == "" {
+		return edgedetection.DefaultDetectionConfig(), nil
+	}
+
+	configReader, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer configReader.Close()
+
+	return edgedetection.ReadDetectionConfig(configReader)
 }
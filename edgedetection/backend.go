@@ -0,0 +1,46 @@
+package edgedetection
+
+import "image"
+
+// ImageBackend implements the image operations of ImageCV.
+// The gocv backend is used when the package is built with cgo,
+// PureGoBackend when it is built with CGO_ENABLED=0 or the
+// "purego" build tag.
+type ImageBackend interface {
+	// FromImage converts an image to grayscale
+	FromImage(img image.Image) (BackendImage, error)
+	// New returns a black image
+	New(rows, cols int) BackendImage
+	// GaussianBlur with an odd kernel size, a sigma of 0 is
+	// computed from the kernel size
+	GaussianBlur(src BackendImage, ksize int, sigmaX, sigmaY float64) BackendImage
+	Canny(src BackendImage, lower, upper float64) BackendImage
+	// Dilate with a rectangular kernel
+	Dilate(src BackendImage, ksize int) BackendImage
+	// Contours returns the outer contours of all non-zero regions
+	Contours(src BackendImage) []Contour
+	// Resize with nearest neighbour interpolation
+	Resize(src BackendImage, fx, fy float64) BackendImage
+	Crop(src BackendImage, r image.Rectangle) BackendImage
+	// Rectangle draws the outline of r in white
+	Rectangle(dst BackendImage, r image.Rectangle, thickness int)
+}
+
+// BackendImage is a single channel 8-bit image of an ImageBackend.
+type BackendImage interface {
+	Rows() int
+	Cols() int
+	At(row, col int) uint8
+	Set(row, col int, value uint8)
+	Mean() float64
+	Clone() BackendImage
+	ToImage() (image.Image, error)
+}
+
+// Contour is the outer border of a region.
+type Contour struct {
+	Points []image.Point
+	Area   float64
+	// Bounds is the bounding rectangle, Max is exclusive
+	Bounds image.Rectangle
+}
@@ -0,0 +1,8 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package edgedetection
+
+func defaultBackend() ImageBackend {
+	return PureGoBackend{}
+}
@@ -0,0 +1,118 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package edgedetection
+
+import (
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+)
+
+// GocvBackend implements the image operations with OpenCV.
+type GocvBackend struct{}
+
+func defaultBackend() ImageBackend {
+	return GocvBackend{}
+}
+
+type gocvImage struct {
+	mat gocv.Mat
+}
+
+// FromImage ...
+func (GocvBackend) FromImage(img image.Image) (BackendImage, error) {
+	mat, err := gocv.ImageToMatRGB(img)
+	if err != nil {
+		return nil, err
+	}
+	gocv.CvtColor(mat, &mat, gocv.ColorBGRToGray)
+	return &gocvImage{mat: mat}, nil
+}
+
+// New ...
+func (GocvBackend) New(rows, cols int) BackendImage {
+	return &gocvImage{mat: gocv.NewMatWithSize(rows, cols, gocv.MatTypeCV8U)}
+}
+
+// GaussianBlur ...
+func (GocvBackend) GaussianBlur(src BackendImage, ksize int, sigmaX, sigmaY float64) BackendImage {
+	dst := gocv.NewMat()
+	gocv.GaussianBlur(src.(*gocvImage).mat, &dst, image.Point{ksize, ksize}, sigmaX, sigmaY, 1)
+	return &gocvImage{mat: dst}
+}
+
+// Canny ...
+func (GocvBackend) Canny(src BackendImage, lower, upper float64) BackendImage {
+	dst := gocv.NewMat()
+	gocv.Canny(src.(*gocvImage).mat, &dst, float32(lower), float32(upper))
+	return &gocvImage{mat: dst}
+}
+
+// Dilate ...
+func (GocvBackend) Dilate(src BackendImage, ksize int) BackendImage {
+	kernel := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(ksize, ksize))
+	dst := gocv.NewMat()
+	gocv.Dilate(src.(*gocvImage).mat, &dst, kernel)
+	return &gocvImage{mat: dst}
+}
+
+// Contours ...
+func (GocvBackend) Contours(src BackendImage) []Contour {
+	points := gocv.FindContours(src.(*gocvImage).mat, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	contours := make([]Contour, len(points))
+	for i, c := range points {
+		contours[i] = Contour{
+			Points: c,
+			Area:   gocv.ContourArea(c),
+			Bounds: gocv.BoundingRect(c),
+		}
+	}
+	return contours
+}
+
+// Resize ...
+func (GocvBackend) Resize(src BackendImage, fx, fy float64) BackendImage {
+	dst := gocv.NewMat()
+	gocv.Resize(src.(*gocvImage).mat, &dst, image.Point{}, fx, fy, gocv.InterpolationNearestNeighbor)
+	return &gocvImage{mat: dst}
+}
+
+// Crop ...
+func (GocvBackend) Crop(src BackendImage, r image.Rectangle) BackendImage {
+	return &gocvImage{mat: src.(*gocvImage).mat.Region(r)}
+}
+
+// Rectangle ...
+func (GocvBackend) Rectangle(dst BackendImage, r image.Rectangle, thickness int) {
+	gocv.Rectangle(&dst.(*gocvImage).mat, r, color.RGBA{255, 255, 255, 255}, thickness)
+}
+
+func (g *gocvImage) Rows() int {
+	return g.mat.Rows()
+}
+
+func (g *gocvImage) Cols() int {
+	return g.mat.Cols()
+}
+
+func (g *gocvImage) At(row, col int) uint8 {
+	return g.mat.GetUCharAt(row, col)
+}
+
+func (g *gocvImage) Set(row, col int, value uint8) {
+	g.mat.SetUCharAt(row, col, value)
+}
+
+func (g *gocvImage) Mean() float64 {
+	return g.mat.Mean().Val1
+}
+
+func (g *gocvImage) Clone() BackendImage {
+	return &gocvImage{mat: g.mat.Clone()}
+}
+
+func (g *gocvImage) ToImage() (image.Image, error) {
+	return g.mat.ToImage()
+}
@@ -0,0 +1,536 @@
+package edgedetection
+
+import (
+	"image"
+	"math"
+)
+
+// PureGoBackend implements the image operations without OpenCV and cgo.
+// It follows the OpenCV defaults: luminance weights 0.299, 0.587 and
+// 0.114, replicated borders for the blur, 3x3 Sobel gradients with L1
+// magnitude for Canny, 8-connected regions for the contours.
+type PureGoBackend struct{}
+
+type pureImage struct {
+	rows int
+	cols int
+	pix  []uint8
+}
+
+func newPureImage(rows, cols int) *pureImage {
+	return &pureImage{rows: rows, cols: cols, pix: make([]uint8, rows*cols)}
+}
+
+// FromImage ...
+func (PureGoBackend) FromImage(img image.Image) (BackendImage, error) {
+	bounds := img.Bounds()
+	dst := newPureImage(bounds.Dy(), bounds.Dx())
+	for y := 0; y < dst.rows; y++ {
+		for x := 0; x < dst.cols; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			gray := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+			dst.pix[y*dst.cols+x] = uint8(math.Min(255, math.Round(gray)))
+		}
+	}
+	return dst, nil
+}
+
+// New ...
+func (PureGoBackend) New(rows, cols int) BackendImage {
+	return newPureImage(rows, cols)
+}
+
+// GaussianBlur ...
+func (PureGoBackend) GaussianBlur(src BackendImage, ksize int, sigmaX, sigmaY float64) BackendImage {
+	s := src.(*pureImage)
+	if sigmaY == 0 {
+		sigmaY = sigmaX
+	}
+	kx := gaussianKernel(ksize, sigmaX)
+	ky := gaussianKernel(ksize, sigmaY)
+
+	// horizontal pass into floats, vertical pass into bytes
+	tmp := make([]float64, len(s.pix))
+	r := ksize / 2
+	for y := 0; y < s.rows; y++ {
+		for x := 0; x < s.cols; x++ {
+			sum := 0.0
+			for k, w := range kx {
+				sum += w * float64(s.pix[y*s.cols+clampInt(x+k-r, 0, s.cols-1)])
+			}
+			tmp[y*s.cols+x] = sum
+		}
+	}
+
+	dst := newPureImage(s.rows, s.cols)
+	for y := 0; y < s.rows; y++ {
+		for x := 0; x < s.cols; x++ {
+			sum := 0.0
+			for k, w := range ky {
+				sum += w * tmp[clampInt(y+k-r, 0, s.rows-1)*s.cols+x]
+			}
+			dst.pix[y*s.cols+x] = uint8(math.Min(255, math.Round(sum)))
+		}
+	}
+	return dst
+}
+
+// gaussianKernel returns a normalised kernel, a sigma <= 0 is
+// computed from the size like OpenCV does.
+func gaussianKernel(ksize int, sigma float64) []float64 {
+	if sigma <= 0 {
+		sigma = 0.3*(float64(ksize-1)*0.5-1) + 0.8
+	}
+
+	kernel := make([]float64, ksize)
+	sum := 0.0
+	for i := range kernel {
+		x := float64(i - ksize/2)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// Canny ...
+func (PureGoBackend) Canny(src BackendImage, lower, upper float64) BackendImage {
+	s := src.(*pureImage)
+	rows, cols := s.rows, s.cols
+
+	// Sobel gradients with reflected borders
+	at := func(y, x int) float64 {
+		return float64(s.pix[reflect101(y, rows)*cols+reflect101(x, cols)])
+	}
+	gx := make([]float64, rows*cols)
+	gy := make([]float64, rows*cols)
+	magnitude := make([]float64, rows*cols)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			i := y*cols + x
+			gx[i] = at(y-1, x+1) + 2*at(y, x+1) + at(y+1, x+1) - at(y-1, x-1) - 2*at(y, x-1) - at(y+1, x-1)
+			gy[i] = at(y+1, x-1) + 2*at(y+1, x) + at(y+1, x+1) - at(y-1, x-1) - 2*at(y-1, x) - at(y-1, x+1)
+			magnitude[i] = math.Abs(gx[i]) + math.Abs(gy[i])
+		}
+	}
+
+	mag := func(y, x int) float64 {
+		if y < 0 || y >= rows || x < 0 || x >= cols {
+			return 0
+		}
+		return magnitude[y*cols+x]
+	}
+
+	// non maximum suppression in four directions
+	const (
+		tan22 = 0.4142135623730950
+		tan67 = 2.4142135623730950
+	)
+	const (
+		none = iota
+		weak
+		strong
+	)
+	state := make([]uint8, rows*cols)
+	stack := make([]int, 0)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			i := y*cols + x
+			m := magnitude[i]
+			if m <= lower {
+				continue
+			}
+
+			ax, ay := math.Abs(gx[i]), math.Abs(gy[i])
+			var n1, n2 float64
+			switch {
+			case ay <= ax*tan22:
+				n1, n2 = mag(y, x-1), mag(y, x+1)
+			case ay >= ax*tan67:
+				n1, n2 = mag(y-1, x), mag(y+1, x)
+			case (gx[i] > 0) == (gy[i] > 0):
+				n1, n2 = mag(y-1, x-1), mag(y+1, x+1)
+			default:
+				n1, n2 = mag(y-1, x+1), mag(y+1, x-1)
+			}
+			if m <= n1 || m < n2 {
+				continue
+			}
+
+			state[i] = weak
+			if m > upper {
+				state[i] = strong
+				stack = append(stack, i)
+			}
+		}
+	}
+
+	// hysteresis, weak edges connected to strong edges are kept
+	dst := newPureImage(rows, cols)
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		dst.pix[i] = 255
+
+		y, x := i/cols, i%cols
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				ny, nx := y+dy, x+dx
+				if ny < 0 || ny >= rows || nx < 0 || nx >= cols {
+					continue
+				}
+				n := ny*cols + nx
+				if state[n] == weak {
+					state[n] = strong
+					stack = append(stack, n)
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Dilate ...
+func (PureGoBackend) Dilate(src BackendImage, ksize int) BackendImage {
+	s := src.(*pureImage)
+	tmp := newPureImage(s.rows, s.cols)
+	for y := 0; y < s.rows; y++ {
+		slidingMax(s.pix[y*s.cols:], tmp.pix[y*s.cols:], s.cols, 1, ksize)
+	}
+
+	dst := newPureImage(s.rows, s.cols)
+	for x := 0; x < s.cols; x++ {
+		slidingMax(tmp.pix[x:], dst.pix[x:], s.rows, s.cols, ksize)
+	}
+	return dst
+}
+
+// slidingMax writes the maximum of a window of ksize values with the
+// anchor in the center, values outside the line are ignored.
+func slidingMax(src, dst []uint8, n, stride, ksize int) {
+	anchor := ksize / 2
+	// deque of indexes with decreasing values
+	deque := make([]int, 0, ksize)
+	next := 0
+	for i := 0; i < n; i++ {
+		last := minInt(i-anchor+ksize-1, n-1)
+		for ; next <= last; next++ {
+			for len(deque) > 0 && src[deque[len(deque)-1]*stride] <= src[next*stride] {
+				deque = deque[:len(deque)-1]
+			}
+			deque = append(deque, next)
+		}
+		for deque[0] < i-anchor {
+			deque = deque[1:]
+		}
+		dst[i*stride] = src[deque[0]*stride]
+	}
+}
+
+// Contours traces the outer border of every 8-connected region that is
+// not inside a hole of another region, like RETR_EXTERNAL. The points
+// are reduced to the corners, like CHAIN_APPROX_SIMPLE.
+func (PureGoBackend) Contours(src BackendImage) []Contour {
+	s := src.(*pureImage)
+	rows, cols := s.rows, s.cols
+	outside := outsideBackground(s)
+
+	visited := make([]bool, rows*cols)
+	var contours []Contour
+	for start := range s.pix {
+		if s.pix[start] == 0 || visited[start] {
+			continue
+		}
+
+		// mark the region and check if it touches the outer background
+		external := false
+		queue := []int{start}
+		visited[start] = true
+		for len(queue) > 0 {
+			i := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			y, x := i/cols, i%cols
+			if y == 0 || x == 0 || y == rows-1 || x == cols-1 {
+				external = true
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					ny, nx := y+dy, x+dx
+					if ny < 0 || ny >= rows || nx < 0 || nx >= cols {
+						continue
+					}
+					n := ny*cols + nx
+					if s.pix[n] == 0 {
+						if (dx == 0 || dy == 0) && outside[n] {
+							external = true
+						}
+						continue
+					}
+					if !visited[n] {
+						visited[n] = true
+						queue = append(queue, n)
+					}
+				}
+			}
+		}
+
+		if external {
+			points := simplifyContour(traceContour(s, start%cols, start/cols))
+			contours = append(contours, Contour{
+				Points: points,
+				Area:   polygonArea(points),
+				Bounds: boundingRect(points),
+			})
+		}
+	}
+	return contours
+}
+
+// outsideBackground marks the background pixels that are 4-connected
+// to the border of the image.
+func outsideBackground(s *pureImage) []bool {
+	rows, cols := s.rows, s.cols
+	outside := make([]bool, rows*cols)
+	var stack []int
+	push := func(y, x int) {
+		i := y*cols + x
+		if s.pix[i] == 0 && !outside[i] {
+			outside[i] = true
+			stack = append(stack, i)
+		}
+	}
+	for x := 0; x < cols; x++ {
+		push(0, x)
+		push(rows-1, x)
+	}
+	for y := 0; y < rows; y++ {
+		push(y, 0)
+		push(y, cols-1)
+	}
+
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		y, x := i/cols, i%cols
+		if y > 0 {
+			push(y-1, x)
+		}
+		if y < rows-1 {
+			push(y+1, x)
+		}
+		if x > 0 {
+			push(y, x-1)
+		}
+		if x < cols-1 {
+			push(y, x+1)
+		}
+	}
+	return outside
+}
+
+// contourDirections are the 8 neighbours in clockwise order.
+var contourDirections = [8]image.Point{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+
+// traceContour follows the border of a region clockwise with Moore
+// neighbour tracing, starting at its first pixel in raster order.
+func traceContour(s *pureImage, x, y int) []image.Point {
+	foreground := func(p image.Point) bool {
+		return p.X >= 0 && p.Y >= 0 && p.X < s.cols && p.Y < s.rows && s.pix[p.Y*s.cols+p.X] != 0
+	}
+
+	start := image.Pt(x, y)
+	contour := []image.Point{start}
+	current := start
+	// the pixel left of the start is background
+	backtrack := 4
+	var second image.Point
+	for {
+		found := -1
+		for k := 1; k <= 8; k++ {
+			d := (backtrack + k) % 8
+			if foreground(current.Add(contourDirections[d])) {
+				found = d
+				break
+			}
+		}
+		// isolated pixel
+		if found < 0 {
+			return contour
+		}
+
+		next := current.Add(contourDirections[found])
+		if current == start {
+			if len(contour) > 1 && next == second {
+				return contour[:len(contour)-1]
+			}
+			if len(contour) == 1 {
+				second = next
+			}
+		}
+
+		// the neighbour checked before next is background, seen from next
+		previous := current.Add(contourDirections[(found+7)%8])
+		backtrack = directionIndex(previous.Sub(next))
+		current = next
+		contour = append(contour, current)
+	}
+}
+
+func directionIndex(d image.Point) int {
+	for i, c := range contourDirections {
+		if c == d {
+			return i
+		}
+	}
+	return 0
+}
+
+// simplifyContour keeps only the points where the direction changes.
+func simplifyContour(points []image.Point) []image.Point {
+	if len(points) < 3 {
+		return points
+	}
+
+	var simple []image.Point
+	n := len(points)
+	for i, p := range points {
+		in := p.Sub(points[(i+n-1)%n])
+		out := points[(i+1)%n].Sub(p)
+		if in != out {
+			simple = append(simple, p)
+		}
+	}
+	if len(simple) == 0 {
+		return points[:1]
+	}
+	return simple
+}
+
+// polygonArea is the shoelace area of the closed polygon.
+func polygonArea(points []image.Point) float64 {
+	area := 0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	return math.Abs(float64(area)) / 2
+}
+
+func boundingRect(points []image.Point) image.Rectangle {
+	r := image.Rectangle{Min: points[0], Max: points[0].Add(image.Pt(1, 1))}
+	for _, p := range points[1:] {
+		r = r.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+	}
+	return r
+}
+
+// Resize ...
+func (PureGoBackend) Resize(src BackendImage, fx, fy float64) BackendImage {
+	s := src.(*pureImage)
+	dst := newPureImage(int(math.Round(float64(s.rows)*fy)), int(math.Round(float64(s.cols)*fx)))
+	for y := 0; y < dst.rows; y++ {
+		sy := minInt(int(float64(y)/fy), s.rows-1)
+		for x := 0; x < dst.cols; x++ {
+			sx := minInt(int(float64(x)/fx), s.cols-1)
+			dst.pix[y*dst.cols+x] = s.pix[sy*s.cols+sx]
+		}
+	}
+	return dst
+}
+
+// Crop ...
+func (PureGoBackend) Crop(src BackendImage, r image.Rectangle) BackendImage {
+	s := src.(*pureImage)
+	r = r.Intersect(image.Rect(0, 0, s.cols, s.rows))
+	dst := newPureImage(r.Dy(), r.Dx())
+	for y := 0; y < dst.rows; y++ {
+		copy(dst.pix[y*dst.cols:(y+1)*dst.cols], s.pix[(r.Min.Y+y)*s.cols+r.Min.X:])
+	}
+	return dst
+}
+
+// Rectangle ...
+func (PureGoBackend) Rectangle(dst BackendImage, r image.Rectangle, thickness int) {
+	d := dst.(*pureImage)
+	outer := image.Rect(r.Min.X-thickness/2, r.Min.Y-thickness/2, r.Max.X+(thickness+1)/2, r.Max.Y+(thickness+1)/2)
+	inner := outer.Inset(thickness)
+	outer = outer.Intersect(image.Rect(0, 0, d.cols, d.rows))
+	for y := outer.Min.Y; y < outer.Max.Y; y++ {
+		for x := outer.Min.X; x < outer.Max.X; x++ {
+			if !image.Pt(x, y).In(inner) {
+				d.pix[y*d.cols+x] = 255
+			}
+		}
+	}
+}
+
+func (p *pureImage) Rows() int {
+	return p.rows
+}
+
+func (p *pureImage) Cols() int {
+	return p.cols
+}
+
+func (p *pureImage) At(row, col int) uint8 {
+	return p.pix[row*p.cols+col]
+}
+
+func (p *pureImage) Set(row, col int, value uint8) {
+	p.pix[row*p.cols+col] = value
+}
+
+func (p *pureImage) Mean() float64 {
+	if len(p.pix) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, v := range p.pix {
+		sum += int(v)
+	}
+	return float64(sum) / float64(len(p.pix))
+}
+
+func (p *pureImage) Clone() BackendImage {
+	return &pureImage{rows: p.rows, cols: p.cols, pix: append([]uint8(nil), p.pix...)}
+}
+
+func (p *pureImage) ToImage() (image.Image, error) {
+	img := image.NewGray(image.Rect(0, 0, p.cols, p.rows))
+	copy(img.Pix, p.pix)
+	return img, nil
+}
+
+func reflect101(i, n int) int {
+	if n == 1 {
+		return 0
+	}
+	for i < 0 || i >= n {
+		if i < 0 {
+			i = -i
+		}
+		if i >= n {
+			i = 2*n - 2 - i
+		}
+	}
+	return i
+}
+
+func clampInt(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
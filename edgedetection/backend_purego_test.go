@@ -0,0 +1,145 @@
+package edgedetection
+
+import (
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fromArt returns an image with 255 for every "#",
+// 100 for every "+" and 0 for every ".".
+func fromArt(art string) *pureImage {
+	lines := strings.Fields(art)
+	img := newPureImage(len(lines), len(lines[0]))
+	for y, line := range lines {
+		for x, c := range line {
+			switch c {
+			case '#':
+				img.Set(y, x, 255)
+			case '+':
+				img.Set(y, x, 100)
+			}
+		}
+	}
+	return img
+}
+
+// toArt draws every non-zero pixel as "#".
+func toArt(img BackendImage) string {
+	var b strings.Builder
+	for y := 0; y < img.Rows(); y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		for x := 0; x < img.Cols(); x++ {
+			if img.At(y, x) != 0 {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestPureGoCanny(t *testing.T) {
+	square := fromArt(`
+		............
+		............
+		............
+		...######...
+		...######...
+		...######...
+		...######...
+		...######...
+		...######...
+		............
+		............
+		............`)
+
+	// like OpenCV the suppression keeps the outer pixel of the top and
+	// left border and the inner pixel of the bottom and right border
+	want := strings.Join(strings.Fields(`
+		............
+		............
+		....####....
+		...#....#...
+		..#.....#...
+		..#.....#...
+		..#.....#...
+		..#.....#...
+		...######...
+		............
+		............
+		............`), "\n")
+
+	if got := toArt(PureGoBackend{}.Canny(square, 100, 300)); got != want {
+		t.Errorf("edges of the square:\n%s\nwant:\n%s", got, want)
+	}
+
+	// the L1 magnitude is at most 2*4*255, with a higher upper
+	// threshold nothing is strong and no weak edge is kept
+	if got := toArt(PureGoBackend{}.Canny(square, 100, 2100)); strings.Contains(got, "#") {
+		t.Errorf("edges without strong pixels:\n%s", got)
+	}
+}
+
+var contourShapes = `
+	..............
+	.#####........
+	.#...#...##...
+	.#.#.#...###..
+	.#...#....##..
+	.#####........
+	..............
+	...........#..
+	.#.........#..
+	....+++....#..`
+
+func TestPureGoContours(t *testing.T) {
+	// the pixel inside the hole of the square has no contour
+	want := []Contour{
+		{
+			Points: []image.Point{{1, 1}, {5, 1}, {5, 5}, {1, 5}},
+			Area:   16,
+			Bounds: image.Rect(1, 1, 6, 6),
+		},
+		{
+			Points: []image.Point{{9, 2}, {10, 2}, {11, 3}, {11, 4}, {10, 4}, {9, 3}},
+			Area:   3,
+			Bounds: image.Rect(9, 2, 12, 5),
+		},
+		{
+			Points: []image.Point{{11, 7}, {11, 9}},
+			Area:   0,
+			Bounds: image.Rect(11, 7, 12, 10),
+		},
+		{
+			Points: []image.Point{{1, 8}},
+			Area:   0,
+			Bounds: image.Rect(1, 8, 2, 9),
+		},
+		{
+			Points: []image.Point{{4, 9}, {6, 9}},
+			Area:   0,
+			Bounds: image.Rect(4, 9, 7, 10),
+		},
+	}
+
+	if got := (PureGoBackend{}).Contours(fromArt(contourShapes)); !reflect.DeepEqual(got, want) {
+		t.Errorf("contours %+v, want %+v", got, want)
+	}
+}
+
+func TestFindContours(t *testing.T) {
+	img := &ImageCV{mat: fromArt(contourShapes), backend: PureGoBackend{}, edgeLevel: 128}
+
+	// the gray line is below the edge level, the lines and the pixel
+	// have no area
+	contours := img.findContours(2)
+	want := []image.Rectangle{image.Rect(1, 1, 6, 6), image.Rect(9, 2, 12, 5)}
+	if !reflect.DeepEqual(img.rects, want) || len(contours) != len(want) {
+		t.Errorf("boxes %v, want %v", img.rects, want)
+	}
+}
@@ -0,0 +1,366 @@
+package edgedetection
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// DetectionConfig holds the parameters of all detection stages.
+// Different rooms and scanners usually need different settings.
+type DetectionConfig struct {
+	// BlurKernel is the odd kernel size of the Gaussian blur
+	BlurKernel int `json:"blurKernel"`
+	// CannySigma spreads the Canny thresholds around the mean intensity
+	CannySigma float64 `json:"cannySigma"`
+	// DilationKernel is the kernel size of the dilatation of the edges
+	DilationKernel int `json:"dilationKernel"`
+	// MinContourArea filters contours with less pixels
+	MinContourArea float64 `json:"minContourArea"`
+	// DepthEdgeJump is the relative depth difference of a depth edge
+	DepthEdgeJump float64 `json:"depthEdgeJump"`
+	// EdgeDetectors replace the single Canny on the BlurKernel blur by
+	// a multi-scale edge confidence map, pixels with a confidence of at
+	// least EdgeThreshold are edges
+	EdgeDetectors []EdgeDetector `json:"edgeDetectors"`
+	EdgeThreshold float64        `json:"edgeThreshold"`
+	// BoxFilter reduces overlapping contour boxes, "suppress" keeps the
+	// box with the larger contour, "merge" replaces them by their union,
+	// "" keeps all boxes. Boxes overlap if their BoxOverlap, "iou" or
+	// "iomin", is above BoxThreshold
+	BoxFilter    string  `json:"boxFilter"`
+	BoxOverlap   string  `json:"boxOverlap"`
+	BoxThreshold float64 `json:"boxThreshold"`
+
+	// GroundIterations and GroundTolerance are the RANSAC iterations
+	// and the inlier distance of the ground plane fit
+	GroundIterations int     `json:"groundIterations"`
+	GroundTolerance  float64 `json:"groundTolerance"`
+	// Up is the approximate up direction of the scan, the ground
+	// normal may be at most GroundMaxTilt degrees away from it
+	Up            [3]float64 `json:"up"`
+	GroundMaxTilt float64    `json:"groundMaxTilt"`
+	// MinGroundDistance and MaxGroundDistance limit the signed
+	// distance of the selected points to the ground plane
+	MinGroundDistance float64 `json:"minGroundDistance"`
+	MaxGroundDistance float64 `json:"maxGroundDistance"`
+	// Planes is the maximum number of planes removed from the selected
+	// points before the clustering, 0 disables the plane segmentation.
+	// A plane needs PlaneMinInliers points within PlaneTolerance whose
+	// normals are at most PlaneMaxNormalAngle degrees off
+	Planes              int     `json:"planes"`
+	PlaneIterations     int     `json:"planeIterations"`
+	PlaneTolerance      float64 `json:"planeTolerance"`
+	PlaneMinInliers     int     `json:"planeMinInliers"`
+	PlaneMaxNormalAngle float64 `json:"planeMaxNormalAngle"`
+	// NormalNeighbours is the number of mesh vertices used for the PCA
+	// normal of a raw point, oriented toward Viewpoint; nil uses the
+	// centroid of the mesh, which is inside a scanned room
+	NormalNeighbours int         `json:"normalNeighbours"`
+	Viewpoint        *[3]float64 `json:"viewpoint"`
+	// Mode is "barycenter" for face barycenters and normals
+	// or "rawpoints" for the mesh vertices
+	Mode string `json:"mode"`
+
+	// Seed makes the random decisions reproducible, nil seeds with the time
+	Seed *int64 `json:"seed"`
+
+	// Debug receives the intermediate images, nil runs headless
+	Debug DebugSink `json:"-"`
+	// Backend implements the image operations, nil uses
+	// the default backend of the build
+	Backend ImageBackend `json:"-"`
+}
+
+// ConfigError is returned for invalid configuration values. Field is
+// the invalid key, or the line of a YAML file outside of the supported subset.
+type ConfigError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("detection config %s: %s", e.Field, e.Msg)
+}
+
+// DefaultDetectionConfig returns the default settings of all stages.
+func DefaultDetectionConfig() *DetectionConfig {
+	return &DetectionConfig{
+		BlurKernel:        7,
+		CannySigma:        0.5,
+		DilationKernel:    100,
+		MinContourArea:    5000,
+		DepthEdgeJump:     DepthEdgeJump,
+		EdgeThreshold:     0.5,
+		BoxOverlap:        "iou",
+		BoxThreshold:      0.5,
+		GroundIterations:  1000,
+		GroundTolerance:   0.02,
+		Up:                [3]float64{0, 0, 1},
+		GroundMaxTilt:     30,
+		MinGroundDistance: 0.02,
+		MaxGroundDistance: 0.5,
+
+		PlaneIterations:     1000,
+		PlaneTolerance:      0.02,
+		PlaneMinInliers:     500,
+		PlaneMaxNormalAngle: 20,
+		NormalNeighbours:    16,
+		Mode:                "barycenter",
+	}
+}
+
+// ReadDetectionConfig reads a JSON or YAML configuration. Missing values
+// keep their defaults, unknown keys are errors. YAML files may only
+// contain "key: value" lines, flow lists like "[0, 0, 1]", block lists
+// of scalars or of flat mappings, quoted strings without escapes and
+// comments, anything else is a *ConfigError.
+func ReadDetectionConfig(r io.Reader) (*DetectionConfig, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		content, err = yamlToJSON(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config := DefaultDetectionConfig()
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(config)
+	if err != nil {
+		return nil, fmt.Errorf("detection config: %v", err)
+	}
+
+	return config, config.Validate()
+}
+
+// yamlToJSON converts a YAML mapping to a JSON object. A key without
+// value starts a block list, its items are scalars ("- canny:2") or
+// flat mappings ("- type: canny" followed by indented "key: value" lines).
+func yamlToJSON(content []byte) ([]byte, error) {
+	values := make(map[string]interface{})
+	var (
+		listKey  string
+		listLine int
+		list     []interface{}
+		// the mapping of the current list item and the indent of its keys
+		item       map[string]interface{}
+		itemIndent int
+	)
+	endList := func() error {
+		if listKey != "" && list == nil {
+			return yamlError(listLine, "key without value")
+		}
+		if listKey != "" {
+			values[listKey] = list
+		}
+		listKey, list, item = "", nil, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, ok := yamlStripComment(scanner.Text())
+		if !ok {
+			return nil, yamlError(line, "unterminated quoted string")
+		}
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " \t"))
+
+		switch {
+		case trimmed == "-" || strings.HasPrefix(trimmed, "- "):
+			if listKey == "" {
+				return nil, yamlError(line, "list item without key")
+			}
+			value := strings.TrimSpace(trimmed[1:])
+			key, pair, ok := yamlPair(value)
+			if !ok {
+				list = append(list, yamlScalar(value))
+				item = nil
+				continue
+			}
+			if pair == "" {
+				return nil, yamlError(line, "nested values are not supported")
+			}
+			item = map[string]interface{}{key: yamlScalar(pair)}
+			itemIndent = indent + len(trimmed) - len(value)
+			list = append(list, item)
+
+		case indent > 0:
+			key, value, ok := yamlPair(trimmed)
+			if item == nil || indent != itemIndent || !ok || value == "" {
+				return nil, yamlError(line, "nested values are not supported")
+			}
+			if _, ok := item[key]; ok {
+				return nil, yamlError(line, fmt.Sprintf("duplicate key %q", key))
+			}
+			item[key] = yamlScalar(value)
+
+		default:
+			if err := endList(); err != nil {
+				return nil, err
+			}
+			i := strings.Index(text, ":")
+			if i < 0 {
+				return nil, yamlError(line, "expected \"key: value\"")
+			}
+			key := strings.TrimSpace(text[:i])
+			value := strings.TrimSpace(text[i+1:])
+			if _, ok := values[key]; ok {
+				return nil, yamlError(line, fmt.Sprintf("duplicate key %q", key))
+			}
+			values[key] = yamlScalar(value)
+			if value == "" {
+				listKey, listLine = key, line
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := endList(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(values)
+}
+
+func yamlError(line int, msg string) error {
+	return &ConfigError{fmt.Sprintf("line %d", line), msg}
+}
+
+// yamlStripComment removes a comment from the line. A comment starts
+// with a "#" at the beginning of the line or after a space outside of
+// a quoted string. A quote only opens a string at the start of a
+// scalar, false if the line ends inside a string.
+func yamlStripComment(text string) (string, bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			before := strings.TrimRight(text[:i], " \t")
+			if before == "" || strings.ContainsAny(before[len(before)-1:], ":-[,") {
+				quote = ch
+			}
+		case ch == '#':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '\t' {
+				return text[:i], true
+			}
+		}
+	}
+	return text, quote == 0
+}
+
+// yamlPair splits "key: value" and "key:". Scalars like "canny:2",
+// quoted strings and flow lists are not pairs.
+func yamlPair(text string) (string, string, bool) {
+	if text == "" || strings.ContainsAny(text[:1], "\"'[{") {
+		return "", "", false
+	}
+	if strings.HasSuffix(text, ":") {
+		return strings.TrimSpace(text[:len(text)-1]), "", true
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:]), true
+}
+
+func yamlScalar(value string) interface{} {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		list := make([]interface{}, 0)
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, yamlScalar(item))
+			}
+		}
+		return list
+	}
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return value
+}
+
+// Validate checks all values and returns a *ConfigError for the first invalid one.
+func (c *DetectionConfig) Validate() error {
+	switch {
+	case c.BlurKernel <= 0 || c.BlurKernel%2 == 0:
+		return &ConfigError{"blurKernel", "must be a positive odd number"}
+	case c.CannySigma < 0 || c.CannySigma > 1:
+		return &ConfigError{"cannySigma", "must be between 0 and 1"}
+	case c.DilationKernel <= 0:
+		return &ConfigError{"dilationKernel", "must be positive"}
+	case c.MinContourArea < 0:
+		return &ConfigError{"minContourArea", "must not be negative"}
+	case c.DepthEdgeJump <= 0:
+		return &ConfigError{"depthEdgeJump", "must be positive"}
+	case c.EdgeThreshold < 0 || c.EdgeThreshold > 1:
+		return &ConfigError{"edgeThreshold", "must be between 0 and 1"}
+	case c.BoxFilter != "" && c.BoxFilter != "suppress" && c.BoxFilter != "merge":
+		return &ConfigError{"boxFilter", fmt.Sprintf("must be \"suppress\", \"merge\" or empty, got %q", c.BoxFilter)}
+	case c.BoxOverlap != "iou" && c.BoxOverlap != "iomin":
+		return &ConfigError{"boxOverlap", fmt.Sprintf("must be \"iou\" or \"iomin\", got %q", c.BoxOverlap)}
+	case c.BoxThreshold < 0 || c.BoxThreshold >= 1:
+		return &ConfigError{"boxThreshold", "must be at least 0 and below 1"}
+	case c.GroundIterations <= 0:
+		return &ConfigError{"groundIterations", "must be positive"}
+	case c.GroundTolerance <= 0:
+		return &ConfigError{"groundTolerance", "must be positive"}
+	case c.Up == [3]float64{}:
+		return &ConfigError{"up", "must not be the zero vector"}
+	case c.GroundMaxTilt < 0 || c.GroundMaxTilt > 90:
+		return &ConfigError{"groundMaxTilt", "must be between 0 and 90 degrees"}
+	case c.MinGroundDistance >= c.MaxGroundDistance:
+		return &ConfigError{"minGroundDistance", "must be below maxGroundDistance"}
+	case c.Planes < 0:
+		return &ConfigError{"planes", "must not be negative"}
+	case c.PlaneIterations <= 0:
+		return &ConfigError{"planeIterations", "must be positive"}
+	case c.PlaneTolerance <= 0:
+		return &ConfigError{"planeTolerance", "must be positive"}
+	case c.PlaneMinInliers < 3:
+		return &ConfigError{"planeMinInliers", "must be at least 3"}
+	case c.PlaneMaxNormalAngle < 0 || c.PlaneMaxNormalAngle > 90:
+		return &ConfigError{"planeMaxNormalAngle", "must be between 0 and 90 degrees"}
+	case c.NormalNeighbours < 3:
+		return &ConfigError{"normalNeighbours", "must be at least 3"}
+	case c.Mode != "barycenter" && c.Mode != "rawpoints":
+		return &ConfigError{"mode", fmt.Sprintf("must be \"barycenter\" or \"rawpoints\", got %q", c.Mode)}
+	}
+	for k, detector := range c.EdgeDetectors {
+		if msg := detector.validate(); msg != "" {
+			return &ConfigError{fmt.Sprintf("edgeDetectors[%d]", k), msg}
+		}
+	}
+	return nil
+}
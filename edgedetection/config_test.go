@@ -0,0 +1,106 @@
+package edgedetection
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDetectionConfigYAMLLists(t *testing.T) {
+	canny2 := EdgeDetector{Type: "canny", Sigma: 2, Weight: 1, Low: 0.1, High: 0.3}
+	log3 := EdgeDetector{Type: "log", Sigma: 3, Weight: 0.5, Low: 0.1, High: 0.3}
+	depth := EdgeDetector{Type: "depth", Sigma: 1, Weight: 1, Low: 0.1, High: 0.3}
+
+	tests := []struct {
+		name string
+		yaml string
+		want []EdgeDetector
+		up   [3]float64
+	}{
+		{
+			name: "flow list",
+			yaml: "edgeDetectors: [canny:2, log:3:0.5, depth]\nup: [0, 1, 0]\n",
+			want: []EdgeDetector{canny2, log3, depth},
+			up:   [3]float64{0, 1, 0},
+		},
+		{
+			name: "block list of scalars",
+			yaml: "edgeDetectors:\n  - canny:2\n  - log:3:0.5 # fine lines\n  - depth\nup:\n  - 0\n  - 1\n  - 0\n",
+			want: []EdgeDetector{canny2, log3, depth},
+			up:   [3]float64{0, 1, 0},
+		},
+		{
+			name: "block list of mappings",
+			yaml: "edgeDetectors:\n  - type: canny\n    sigma: 2\n  - type: log\n    sigma: 3\n    weight: 0.5\n  - type: depth\n",
+			want: []EdgeDetector{canny2, log3, depth},
+			up:   [3]float64{0, 0, 1},
+		},
+		{
+			name: "mixed list without indent",
+			yaml: "edgeDetectors:\n- canny:2\n- type: log\n  sigma: 3\n  weight: 0.5\n- depth\nedgeThreshold: 0.4\n",
+			want: []EdgeDetector{canny2, log3, depth},
+			up:   [3]float64{0, 0, 1},
+		},
+	}
+
+	for _, test := range tests {
+		config, err := ReadDetectionConfig(strings.NewReader(test.yaml))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.EdgeDetectors, test.want) {
+			t.Errorf("%s: detectors %+v, want %+v", test.name, config.EdgeDetectors, test.want)
+		}
+		if config.Up != test.up {
+			t.Errorf("%s: up %v, want %v", test.name, config.Up, test.up)
+		}
+	}
+}
+
+func TestReadDetectionConfigYAMLErrors(t *testing.T) {
+	for _, yaml := range []string{
+		"- canny\n",
+		"edgeDetectors:\n  - type: canny\n      sigma: 2\n",
+		"edgeDetectors:\n  - type:\n      name: canny\n",
+		"ground:\n  tolerance: 0.1\n",
+		"edgeDetectors:\n  - type: canny\n    type: log\n",
+	} {
+		if _, err := ReadDetectionConfig(strings.NewReader(yaml)); err == nil {
+			t.Errorf("no error for %q", yaml)
+		}
+	}
+}
+
+func TestReadDetectionConfigYAMLQuotes(t *testing.T) {
+	config, err := ReadDetectionConfig(strings.NewReader("mode: \"rawpoints\" # no barycenters\nboxFilter: 'merge' #kept\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != "rawpoints" || config.BoxFilter != "merge" {
+		t.Errorf("mode %q box filter %q, want rawpoints and merge", config.Mode, config.BoxFilter)
+	}
+
+	_, err = ReadDetectionConfig(strings.NewReader("mode: \"raw #points\"\n"))
+	if e, ok := err.(*ConfigError); !ok || e.Field != "mode" || !strings.Contains(e.Msg, "raw #points") {
+		t.Errorf("quoted # was stripped: %v", err)
+	}
+}
+
+func TestReadDetectionConfigYAMLUnsupported(t *testing.T) {
+	for _, test := range []struct {
+		yaml string
+		line string
+	}{
+		{"mode:\n", "line 1"},
+		{"mode:\nup: [0, 1, 0]\n", "line 1"},
+		{"up: [0, 1, 0]\nedgeDetectors: # later\n\nedgeThreshold: 0.4\n", "line 2"},
+		{"mode: \"rawpoints\n", "line 1"},
+		{"up: [0, 1, 0]\n- canny\n", "line 2"},
+	} {
+		_, err := ReadDetectionConfig(strings.NewReader(test.yaml))
+		if e, ok := err.(*ConfigError); !ok || e.Field != test.line {
+			t.Errorf("%q: error %v, want a *ConfigError on %s", test.yaml, err, test.line)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package edgedetection
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+
+	fatih "github.com/fatih/color"
+)
+
+// DebugSink receives the intermediate images of the detection,
+// like the original texture, the edges and the white points.
+// A nil sink discards all images, so the detection runs headless.
+type DebugSink interface {
+	Image(name string, img image.Image) error
+}
+
+// DirSink writes every debug image as numbered PNG file into a directory.
+type DirSink struct {
+	Dir string
+
+	mutex sync.Mutex
+	count int
+}
+
+// NewDirSink creates the directory if it does not exist.
+func NewDirSink(dir string) (*DirSink, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &DirSink{Dir: dir}, nil
+}
+
+// Image writes the image to "<number>_<name>.png".
+func (s *DirSink) Image(name string, img image.Image) error {
+	s.mutex.Lock()
+	s.count++
+	filename := filepath.Join(s.Dir, fmt.Sprintf("%02d_%s.png", s.count, name))
+	s.mutex.Unlock()
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, img)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// MemorySink keeps all debug images in the order they were received.
+type MemorySink struct {
+	mutex  sync.Mutex
+	names  []string
+	images []image.Image
+}
+
+// Image ...
+func (s *MemorySink) Image(name string, img image.Image) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.names = append(s.names, name)
+	s.images = append(s.images, img)
+	return nil
+}
+
+// Names returns the names of all received images.
+func (s *MemorySink) Names() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.names...)
+}
+
+// Get returns the last image with the name or nil.
+func (s *MemorySink) Get(name string) image.Image {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := len(s.names) - 1; i >= 0; i-- {
+		if s.names[i] == name {
+			return s.images[i]
+		}
+	}
+	return nil
+}
+
+// debugImage sends the image to the sink, errors are only reported
+// because debug images must not stop the detection.
+func (icv *ImageCV) debugImage(sink DebugSink, name string) {
+	if sink == nil {
+		return
+	}
+
+	img, err := icv.mat.ToImage()
+	if err == nil {
+		err = sink.Image(name, img)
+	}
+	if err != nil {
+		fatih.Red("Cannot write debug image "+name+":", err)
+	}
+}
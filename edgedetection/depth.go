@@ -0,0 +1,296 @@
+package edgedetection
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DepthEdgeJump is the relative depth difference between two
+// neighbouring pixels that is treated as a depth edge.
+const DepthEdgeJump = 0.05
+
+// DepthMap is a depth image in metres, stored row by row.
+// Pixels without a measurement are 0.
+type DepthMap struct {
+	Width  int
+	Height int
+	Values []float64
+}
+
+// DepthFormat describes how the values of a depth file are converted to metres.
+// Width, Height, Type, ByteOrder and Offset are only used for raw binary
+// files, they are usually read from a JSON sidecar file with ReadDepthHeader.
+type DepthFormat struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Type is "uint16", "float32" or "float64"
+	Type string `json:"type"`
+	// ByteOrder is "little" (default) or "big"
+	ByteOrder string `json:"byteOrder"`
+	// Offset is the number of bytes before the first value
+	Offset int `json:"offset"`
+	// Scale is multiplied with every stored value, 0 means 1
+	Scale float64 `json:"scale"`
+	// Units of the scaled values: "m" (default), "cm", "mm" or "um"
+	Units string `json:"units"`
+}
+
+// ReadDepthHeader reads the JSON sidecar header of a raw depth file.
+func ReadDepthHeader(r io.Reader) (DepthFormat, error) {
+	var format DepthFormat
+	err := json.NewDecoder(r).Decode(&format)
+	if err != nil {
+		return format, fmt.Errorf("depth header: %v", err)
+	}
+	return format, nil
+}
+
+// factor returns the factor from a stored value to metres.
+func (f DepthFormat) factor() (float64, error) {
+	scale := f.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	switch f.Units {
+	case "", "m":
+		return scale, nil
+	case "cm":
+		return scale * 1e-2, nil
+	case "mm":
+		return scale * 1e-3, nil
+	case "um":
+		return scale * 1e-6, nil
+	}
+	return 0, fmt.Errorf("depth: unknown units %q", f.Units)
+}
+
+// ReadDepthFile reads a depth map. If format.Type is set the file is raw
+// binary, otherwise 16-bit grayscale PNG, 32-bit float TIFF and ASCII
+// files with one row of whitespace separated values per line are
+// detected by their first bytes. NaN, infinite and negative values
+// are treated as missing.
+func ReadDepthFile(depthReader io.Reader, format DepthFormat) (*DepthMap, error) {
+	factor, err := format.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	var depth *DepthMap
+	if format.Type != "" {
+		depth, err = readRawDepth(depthReader, format)
+	} else {
+		reader := bufio.NewReader(depthReader)
+		head, _ := reader.Peek(8)
+		switch {
+		case bytes.HasPrefix(head, []byte("\x89PNG")):
+			depth, err = readPngDepth(reader)
+		case bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*")):
+			depth, err = readTiffDepth(reader)
+		default:
+			depth, err = readAsciiDepth(reader)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range depth.Values {
+		v *= factor
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			v = 0
+		}
+		depth.Values[i] = v
+	}
+	return depth, nil
+}
+
+func readRawDepth(r io.Reader, format DepthFormat) (*DepthMap, error) {
+	if format.Width <= 0 || format.Height <= 0 {
+		return nil, fmt.Errorf("depth: raw file needs width and height")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	switch format.ByteOrder {
+	case "", "little":
+	case "big":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("depth: unknown byte order %q", format.ByteOrder)
+	}
+
+	size := map[string]int{"uint16": 2, "float32": 4, "float64": 8}[format.Type]
+	if size == 0 {
+		return nil, fmt.Errorf("depth: unknown type %q", format.Type)
+	}
+
+	_, err := io.CopyN(ioutil.Discard, r, int64(format.Offset))
+	if err != nil {
+		return nil, fmt.Errorf("depth: cannot skip offset: %v", err)
+	}
+	data := make([]byte, format.Width*format.Height*size)
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		return nil, fmt.Errorf("depth: raw file is shorter than %dx%d %s values", format.Width, format.Height, format.Type)
+	}
+
+	depth := newDepthMap(format.Width, format.Height)
+	for i := range depth.Values {
+		depth.Values[i] = decodeSample(data[i*size:], format.Type, order)
+	}
+	return depth, nil
+}
+
+// decodeSample decodes one uint16, float32 or float64 value.
+func decodeSample(b []byte, kind string, order binary.ByteOrder) float64 {
+	switch kind {
+	case "uint16":
+		return float64(order.Uint16(b))
+	case "float32":
+		return float64(math.Float32frombits(order.Uint32(b)))
+	}
+	return math.Float64frombits(order.Uint64(b))
+}
+
+func readPngDepth(r io.Reader) (*DepthMap, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("depth: %v", err)
+	}
+
+	bounds := img.Bounds()
+	depth := newDepthMap(bounds.Dx(), bounds.Dy())
+	switch gray := img.(type) {
+	case *image.Gray16:
+		for y := 0; y < depth.Height; y++ {
+			for x := 0; x < depth.Width; x++ {
+				depth.Values[y*depth.Width+x] = float64(gray.Gray16At(bounds.Min.X+x, bounds.Min.Y+y).Y)
+			}
+		}
+	case *image.Gray:
+		for y := 0; y < depth.Height; y++ {
+			for x := 0; x < depth.Width; x++ {
+				depth.Values[y*depth.Width+x] = float64(gray.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("depth: png must be a grayscale image")
+	}
+	return depth, nil
+}
+
+func readAsciiDepth(r io.Reader) (*DepthMap, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	depth := &DepthMap{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if depth.Width == 0 {
+			depth.Width = len(fields)
+		}
+		if len(fields) != depth.Width {
+			return nil, fmt.Errorf("depth line %d: expected %d values, got %d", line, depth.Width, len(fields))
+		}
+
+		for _, f := range fields {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, fmt.Errorf("depth line %d: invalid number %q", line, f)
+			}
+			depth.Values = append(depth.Values, v)
+		}
+		depth.Height++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if depth.Height == 0 {
+		return nil, fmt.Errorf("depth: file is empty")
+	}
+	return depth, nil
+}
+
+func newDepthMap(width, height int) *DepthMap {
+	return &DepthMap{
+		Width:  width,
+		Height: height,
+		Values: make([]float64, width*height),
+	}
+}
+
+// At returns the depth of a pixel, 0 if it is missing or outside the map.
+func (m *DepthMap) At(x, y int) float64 {
+	if x < 0 || y < 0 || x >= m.Width || y >= m.Height {
+		return 0
+	}
+	return m.Values[y*m.Width+x]
+}
+
+// Register resamples the depth map to the resolution of the texture.
+// Depth and texture have to cover the same view. Nearest neighbour
+// sampling is used, so no depth is interpolated across edges.
+func (m *DepthMap) Register(width, height int) *DepthMap {
+	if width == m.Width && height == m.Height {
+		return m
+	}
+
+	registered := newDepthMap(width, height)
+	for y := 0; y < height; y++ {
+		sy := (2*y + 1) * m.Height / (2 * height)
+		for x := 0; x < width; x++ {
+			sx := (2*x + 1) * m.Width / (2 * width)
+			registered.Values[y*width+x] = m.Values[sy*m.Width+sx]
+		}
+	}
+	return registered
+}
+
+// Edges marks the pixels in front of a depth discontinuity, where the
+// depth to the right or lower neighbour differs by more than jump
+// times the nearer depth. Pixels next to missing depth are not marked.
+func (m *DepthMap) Edges(jump float64) []bool {
+	edges := make([]bool, len(m.Values))
+	mark := func(a, b int) {
+		da, db := m.Values[a], m.Values[b]
+		if da == 0 || db == 0 {
+			return
+		}
+		if math.Abs(da-db) > jump*math.Min(da, db) {
+			if da < db {
+				edges[a] = true
+			} else {
+				edges[b] = true
+			}
+		}
+	}
+
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			i := y*m.Width + x
+			if x+1 < m.Width {
+				mark(i, i+1)
+			}
+			if y+1 < m.Height {
+				mark(i, i+m.Width)
+			}
+		}
+	}
+	return edges
+}
@@ -1,20 +1,34 @@
 package edgedetection
 
 import (
+	"bytes"
+	"fmt"
+	"image"
 	"io"
+	"math/rand"
 
+	"github.com/edgeDetection/geometry"
 	"github.com/go-gl/mathgl/mgl64"
 )
 
 type Data struct {
-	img *ImageCV
+	img  *ImageCV
+	mesh *Mesh
 
-	depthFile []float64
+	// depth is registered to the texture, nil without depth input
+	depth  *DepthMap
+	config *DetectionConfig
+	// ground is the dominant plane below the points
+	ground geometry.Plane
 
 	coordXYZ []mgl64.Vec3
 	coordUV  []mgl64.Vec2
 
 	Points [][]float64
+	// PointNormals and Curvature are estimated for every
+	// entry of Points in rawpoints mode
+	PointNormals [][]float64
+	Curvature    []float64
 
 	indexXYZ [][3]int
 	indexUV  [][3]int
@@ -24,23 +38,91 @@ type Data struct {
 
 	Normale    [][]float64
 	Barycenter [][]float64
+	// Faces, Pixels and Regions hold the source face, texture pixel and
+	// region of interest of every sample, the Normale and Barycenter
+	// entries in barycenter mode and the Points in rawpoints mode
+	Faces   []int
+	Pixels  []image.Point
+	Regions []int
+	// Planes were removed from the points before the clustering
+	Planes []PlaneSegment
+
+	// random decisions, set by Seed or RandSource
+	random *rand.Rand
 }
 
-func Detection(meshReader, depthReader, imgReader io.Reader) *Data {
+// Detection reads a mesh in any format supported by ReadMesh and
+// a depth map in any format supported by ReadDepthFile, depthReader may be nil.
+// If imgReader is nil, the texture embedded in the mesh is used.
+// A nil config uses DefaultDetectionConfig.
+func Detection(meshReader, depthReader, imgReader io.Reader, config *DetectionConfig) (*Data, error) {
+
+	mesh, err := ReadMesh(meshReader)
+	if err != nil {
+		return nil, err
+	}
+
+	var depth *DepthMap
+	if depthReader != nil {
+		depth, err = ReadDepthFile(depthReader, DepthFormat{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return DetectionMesh(mesh, depth, imgReader, config)
+}
+
+// DetectionMesh runs the detection on a mesh read by any MeshLoader.
+// depth may be nil, otherwise it is registered to the texture resolution.
+func DetectionMesh(mesh *Mesh, depth *DepthMap, imgReader io.Reader, config *DetectionConfig) (*Data, error) {
+	if config == nil {
+		config = DefaultDetectionConfig()
+	}
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
 
-	verticesXYZ, verticesUV, xyzs, uvs := ReadObjFile(meshReader)
+	if imgReader == nil {
+		if mesh.Texture == nil {
+			return nil, fmt.Errorf("no texture image for the mesh")
+		}
+		imgReader = bytes.NewReader(mesh.Texture)
+	}
+
+	img, err := ImageControler(imgReader, depth, config)
+	if err != nil {
+		return nil, err
+	}
+
+	xyzs, uvs := mesh.faceIndexes()
 	d := &Data{
-		img:       ImageControler(imgReader),
-		depthFile: ReadDepthFile(depthReader),
-		coordXYZ:  verticesXYZ,
-		coordUV:   verticesUV,
-		indexXYZ:  xyzs,
-		indexUV:   uvs,
+		img:      img,
+		mesh:     mesh,
+		depth:    img.depth,
+		config:   config,
+		coordXYZ: mesh.Positions,
+		coordUV:  mesh.UVs,
+		indexXYZ: xyzs,
+		indexUV:  uvs,
 	}
 
 	// Barycenter or raw points
-	whitePoints := d.correspondendingPoints("barycenter")
-	whitePoints.showImg("whitePoints")
+	if config.Seed != nil {
+		d.Seed(*config.Seed)
+	}
+
+	whitePoints, err := d.correspondendingPoints()
+	if err != nil {
+		return nil, err
+	}
+	if config.Planes > 0 {
+		err = d.segmentPlanes()
+		if err != nil {
+			return nil, err
+		}
+	}
+	whitePoints.debugImage(config.Debug, "whitePoints")
 
-	return d
+	return d, nil
 }
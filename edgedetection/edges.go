@@ -0,0 +1,301 @@
+package edgedetection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EdgeDetector is one detector of the multi-scale edge stage.
+// Every detector returns an edge confidence between 0 and 1 per pixel,
+// the weighted mean of all detectors is the edge confidence map.
+type EdgeDetector struct {
+	// Type is "canny", "sobel", "log" (Laplacian of Gaussian)
+	// or "depth" for the edges of the depth map
+	Type string `json:"type"`
+	// Sigma is the scale of the Gaussian smoothing in pixels,
+	// it is not used by the depth detector
+	Sigma  float64 `json:"sigma"`
+	Weight float64 `json:"weight"`
+	// Low and High are the hysteresis thresholds of sobel and log
+	// relative to the strongest response. Responses above High
+	// have a confidence of 1
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+func defaultEdgeDetector() EdgeDetector {
+	return EdgeDetector{Sigma: 1, Weight: 1, Low: 0.1, High: 0.3}
+}
+
+// ParseEdgeDetector parses the short form "type[:sigma[:weight]]" of a
+// detector, for example "canny:2" or "sobel:1:0.5".
+func ParseEdgeDetector(s string) (EdgeDetector, error) {
+	detector := defaultEdgeDetector()
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return detector, fmt.Errorf("edge detector %q: expected \"type[:sigma[:weight]]\"", s)
+	}
+
+	detector.Type = strings.TrimSpace(parts[0])
+	for k, field := range []*float64{&detector.Sigma, &detector.Weight} {
+		if k+1 >= len(parts) {
+			break
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[k+1]), 64)
+		if err != nil {
+			return detector, fmt.Errorf("edge detector %q: %v", s, err)
+		}
+		*field = v
+	}
+	return detector, nil
+}
+
+// UnmarshalJSON reads a detector from an object or from the
+// short form of ParseEdgeDetector.
+func (e *EdgeDetector) UnmarshalJSON(data []byte) error {
+	var short string
+	if json.Unmarshal(data, &short) == nil {
+		detector, err := ParseEdgeDetector(short)
+		if err != nil {
+			return err
+		}
+		*e = detector
+		return nil
+	}
+
+	type plain EdgeDetector
+	detector := plain(defaultEdgeDetector())
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&detector)
+	if err != nil {
+		return err
+	}
+	*e = EdgeDetector(detector)
+	return nil
+}
+
+func (e EdgeDetector) validate() string {
+	switch {
+	case e.Type != "canny" && e.Type != "sobel" && e.Type != "log" && e.Type != "depth":
+		return fmt.Sprintf("type must be \"canny\", \"sobel\", \"log\" or \"depth\", got %q", e.Type)
+	case e.Type != "depth" && e.Sigma <= 0:
+		return "sigma must be positive"
+	case e.Weight < 0:
+		return "weight must not be negative"
+	case e.Low < 0 || e.Low > e.High || e.High <= 0 || e.High > 1:
+		return "low and high must be 0 <= low <= high <= 1, high positive"
+	}
+	return ""
+}
+
+// edgeConfidence runs the edge detectors of the config on the image and
+// returns the weighted mean of their confidences scaled to 0..255.
+// Depth detectors use the registered depth map of the image and are
+// skipped without one.
+func (i *ImageCV) edgeConfidence(config *DetectionConfig) *ImageCV {
+	rows, cols := i.mat.Rows(), i.mat.Cols()
+	confidence := make([]float32, rows*cols)
+	total := 0.
+
+	for k, detector := range config.EdgeDetectors {
+		var edges []float32
+		switch detector.Type {
+		case "canny":
+			blurred := i.blurSigma(detector.Sigma)
+			edges = blurred.canny(config.CannySigma).values()
+		case "sobel":
+			edges = hysteresisConfidence(sobelMagnitude(i.blurSigma(detector.Sigma).values(), rows, cols), rows, cols, detector.Low, detector.High)
+		case "log":
+			edges = hysteresisConfidence(logCrossings(i.blurSigma(detector.Sigma).values(), rows, cols, detector.Sigma), rows, cols, detector.Low, detector.High)
+		case "depth":
+			if i.depth == nil {
+				continue
+			}
+			edges = make([]float32, rows*cols)
+			for p, edge := range i.depth.Edges(config.DepthEdgeJump) {
+				if edge {
+					edges[p] = 1
+				}
+			}
+		}
+
+		i.fromValues(edges).debugImage(config.Debug, fmt.Sprintf("edges_%d_%s", k, detector.Type))
+		w := float32(detector.Weight)
+		for p, e := range edges {
+			confidence[p] += w * e
+		}
+		total += detector.Weight
+	}
+
+	if total > 0 {
+		for p := range confidence {
+			confidence[p] /= float32(total)
+		}
+	}
+	return i.fromValues(confidence)
+}
+
+// edgeLevel returns the pixel value of an edge confidence threshold,
+// at least 1 so that a threshold of 0 accepts every edge pixel.
+func edgeLevel(threshold float64) uint8 {
+	level := math.Round(threshold * 255)
+	if level < 1 {
+		return 1
+	}
+	return uint8(level)
+}
+
+// blurSigma blurs with a Gaussian of the given sigma.
+func (i *ImageCV) blurSigma(sigma float64) *ImageCV {
+	ksize := 2*int(math.Ceil(3*sigma)) + 1
+	return i.gauSSianBlur(sigma, sigma, ksize)
+}
+
+// values returns the pixels scaled to 0..1, row major.
+func (i *ImageCV) values() []float32 {
+	rows, cols := i.mat.Rows(), i.mat.Cols()
+	values := make([]float32, rows*cols)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			values[y*cols+x] = float32(i.mat.At(y, x)) / 255
+		}
+	}
+	return values
+}
+
+// fromValues returns an image of the same size with the
+// row major values 0..1 scaled to 0..255.
+func (i *ImageCV) fromValues(values []float32) *ImageCV {
+	rows, cols := i.mat.Rows(), i.mat.Cols()
+	mat := i.backend.New(rows, cols)
+	for p, v := range values {
+		if v > 0 {
+			mat.Set(p/cols, p%cols, uint8(math.Round(math.Min(1, float64(v))*255)))
+		}
+	}
+	return i.with(mat)
+}
+
+// threshold returns a binary image of the pixels of at least level.
+func (i *ImageCV) threshold(level uint8) *ImageCV {
+	rows, cols := i.mat.Rows(), i.mat.Cols()
+	mat := i.backend.New(rows, cols)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			if i.mat.At(y, x) >= level {
+				mat.Set(y, x, 255)
+			}
+		}
+	}
+	return i.with(mat)
+}
+
+// sobelMagnitude returns the Euclidean Sobel gradient magnitude
+// with replicated borders.
+func sobelMagnitude(values []float32, rows, cols int) []float32 {
+	at := func(y, x int) float32 {
+		return values[clampInt(y, 0, rows-1)*cols+clampInt(x, 0, cols-1)]
+	}
+	magnitude := make([]float32, rows*cols)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			gx := at(y-1, x+1) + 2*at(y, x+1) + at(y+1, x+1) - at(y-1, x-1) - 2*at(y, x-1) - at(y+1, x-1)
+			gy := at(y+1, x-1) + 2*at(y+1, x) + at(y+1, x+1) - at(y-1, x-1) - 2*at(y-1, x) - at(y-1, x+1)
+			magnitude[y*cols+x] = float32(math.Hypot(float64(gx), float64(gy)))
+		}
+	}
+	return magnitude
+}
+
+// logCrossings returns the strength of the zero crossings of the scale
+// normalised Laplacian of the blurred values, 0 elsewhere. A crossing
+// is marked on the pixel of the pair with the smaller magnitude.
+func logCrossings(values []float32, rows, cols int, sigma float64) []float32 {
+	at := func(y, x int) float32 {
+		return values[clampInt(y, 0, rows-1)*cols+clampInt(x, 0, cols-1)]
+	}
+	scale := float32(sigma * sigma)
+	laplacian := make([]float32, rows*cols)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			laplacian[y*cols+x] = scale * (at(y-1, x) + at(y+1, x) + at(y, x-1) + at(y, x+1) - 4*at(y, x))
+		}
+	}
+
+	crossings := make([]float32, rows*cols)
+	mark := func(a, b int) {
+		la, lb := laplacian[a], laplacian[b]
+		if (la < 0) == (lb < 0) {
+			return
+		}
+		strength := float32(math.Abs(float64(la - lb)))
+		if math.Abs(float64(lb)) < math.Abs(float64(la)) {
+			a = b
+		}
+		if strength > crossings[a] {
+			crossings[a] = strength
+		}
+	}
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			p := y*cols + x
+			if x+1 < cols {
+				mark(p, p+1)
+			}
+			if y+1 < rows {
+				mark(p, p+cols)
+			}
+		}
+	}
+	return crossings
+}
+
+// hysteresisConfidence keeps the responses above high times the strongest
+// response and the responses above low connected to them. The confidence
+// is the response relative to the high threshold, at most 1.
+func hysteresisConfidence(response []float32, rows, cols int, low, high float64) []float32 {
+	var max float32
+	for _, r := range response {
+		if r > max {
+			max = r
+		}
+	}
+	confidence := make([]float32, rows*cols)
+	if max == 0 {
+		return confidence
+	}
+	lower := float32(low) * max
+	upper := float32(high) * max
+
+	var stack []int
+	for p, r := range response {
+		if r >= upper {
+			confidence[p] = 1
+			stack = append(stack, p)
+		}
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		y, x := p/cols, p%cols
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				ny, nx := y+dy, x+dx
+				if ny < 0 || ny >= rows || nx < 0 || nx >= cols {
+					continue
+				}
+				n := ny*cols + nx
+				if confidence[n] == 0 && response[n] >= lower && response[n] > 0 {
+					confidence[n] = response[n] / upper
+					stack = append(stack, n)
+				}
+			}
+		}
+	}
+	return confidence
+}
@@ -0,0 +1,306 @@
+package edgedetection
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// ClusterFootprint is the area a cluster covers in the texture.
+type ClusterFootprint struct {
+	Label int
+	// Faces is the number of faces projected into the texture
+	Faces int
+	// Pixels is the number of pixels set in Mask
+	Pixels int
+	// Bounds is the pixel bounding box, Max is exclusive
+	Bounds image.Rectangle
+	// Mask covers Bounds, the pixels of the cluster are 255
+	Mask *image.Gray
+	// Hull is the convex hull of the mask pixels, clockwise on the screen
+	Hull []image.Point
+}
+
+// footprint collects the triangles and pixels of one cluster
+// before its mask is filled.
+type footprint struct {
+	faces     int
+	triangles [][3]mgl64.Vec2
+	pixels    []image.Point
+	bounds    image.Rectangle
+}
+
+func (f *footprint) add(r image.Rectangle) {
+	if f.bounds.Empty() {
+		f.bounds = r
+		return
+	}
+	f.bounds = f.bounds.Union(r)
+}
+
+// ClusterFootprints projects the faces of every cluster through their
+// texture coordinates into the texture. sampleLabels holds the label of
+// every sample, aligned with Faces, noise is labelled -1.
+// The footprints are sorted by label.
+func (d *Data) ClusterFootprints(sampleLabels []int) ([]ClusterFootprint, error) {
+	if len(sampleLabels) != len(d.Faces) {
+		return nil, fmt.Errorf("got %d labels for %d samples", len(sampleLabels), len(d.Faces))
+	}
+
+	texture := d.TextureBounds()
+	footprints := make(map[int]*footprint)
+	get := func(label int) *footprint {
+		f, ok := footprints[label]
+		if !ok {
+			f = &footprint{}
+			footprints[label] = f
+		}
+		return f
+	}
+
+	for face, label := range d.FaceLabels(sampleLabels) {
+		if label < 0 {
+			continue
+		}
+		f := get(label)
+		f.faces++
+
+		// faces with missing texture coordinates only mark their vertices
+		var triangle [3]mgl64.Vec2
+		complete := true
+		for i, iuv := range d.indexUV[face] {
+			if iuv < 0 {
+				complete = false
+				continue
+			}
+			triangle[i] = d.uvPoint(d.coordUV[iuv])
+			if p := image.Pt(int(triangle[i].X()), int(triangle[i].Y())); p.In(texture) {
+				f.pixels = append(f.pixels, p)
+				f.add(image.Rectangle{p, p.Add(image.Pt(1, 1))})
+			}
+		}
+		if !complete {
+			continue
+		}
+		if r := triangleBounds(triangle).Intersect(texture); !r.Empty() {
+			f.triangles = append(f.triangles, triangle)
+			f.add(r)
+		}
+	}
+
+	// the sample pixels, raw points do not cover their faces
+	for i, label := range sampleLabels {
+		if label < 0 || i >= len(d.Pixels) {
+			continue
+		}
+		f := get(label)
+		f.pixels = append(f.pixels, d.Pixels[i])
+		f.add(image.Rectangle{d.Pixels[i], d.Pixels[i].Add(image.Pt(1, 1))})
+	}
+
+	result := make([]ClusterFootprint, 0, len(footprints))
+	for label, f := range footprints {
+		if f.bounds.Empty() {
+			continue
+		}
+		mask := image.NewGray(f.bounds)
+		for _, t := range f.triangles {
+			fillTriangle(mask, t)
+		}
+		for _, p := range f.pixels {
+			mask.SetGray(p.X, p.Y, color.Gray{255})
+		}
+
+		pixels, hull := maskHull(mask)
+		result = append(result, ClusterFootprint{
+			Label:  label,
+			Faces:  f.faces,
+			Pixels: pixels,
+			Bounds: f.bounds,
+			Mask:   mask,
+			Hull:   hull,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Label < result[j].Label })
+	return result, nil
+}
+
+// uvPoint maps a texture coordinate to continuous pixel coordinates,
+// the integer part is the pixel of checkCurrentPixel.
+func (d *Data) uvPoint(uv mgl64.Vec2) mgl64.Vec2 {
+	return mgl64.Vec2{uv.X() * float64(d.img.width), (1-uv.Y())*float64(d.img.height) - 1}
+}
+
+func triangleBounds(t [3]mgl64.Vec2) image.Rectangle {
+	minX := math.Min(t[0].X(), math.Min(t[1].X(), t[2].X()))
+	minY := math.Min(t[0].Y(), math.Min(t[1].Y(), t[2].Y()))
+	maxX := math.Max(t[0].X(), math.Max(t[1].X(), t[2].X()))
+	maxY := math.Max(t[0].Y(), math.Max(t[1].Y(), t[2].Y()))
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Floor(maxX))+1, int(math.Floor(maxY))+1)
+}
+
+// fillTriangle sets every pixel of mask whose center is inside t.
+func fillTriangle(mask *image.Gray, t [3]mgl64.Vec2) {
+	edge := func(a, b mgl64.Vec2, x, y float64) float64 {
+		return (b.X()-a.X())*(y-a.Y()) - (b.Y()-a.Y())*(x-a.X())
+	}
+	area := edge(t[0], t[1], t[2].X(), t[2].Y())
+	if area == 0 {
+		return
+	}
+
+	r := triangleBounds(t).Intersect(mask.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		cy := float64(y) + 0.5
+		for x := r.Min.X; x < r.Max.X; x++ {
+			cx := float64(x) + 0.5
+			w0 := edge(t[1], t[2], cx, cy) * area
+			w1 := edge(t[2], t[0], cx, cy) * area
+			w2 := edge(t[0], t[1], cx, cy) * area
+			if w0 >= 0 && w1 >= 0 && w2 >= 0 {
+				mask.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+}
+
+// maskHull returns the number of set pixels of mask and their convex hull.
+// Only the outermost pixels of every row can be hull vertices.
+func maskHull(mask *image.Gray) (int, []image.Point) {
+	pixels := 0
+	var extremes []image.Point
+	r := mask.Rect
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		left, right := -1, -1
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if mask.GrayAt(x, y).Y == 0 {
+				continue
+			}
+			pixels++
+			if left < 0 {
+				left = x
+			}
+			right = x
+		}
+		if left >= 0 {
+			extremes = append(extremes, image.Pt(left, y))
+			if right != left {
+				extremes = append(extremes, image.Pt(right, y))
+			}
+		}
+	}
+	return pixels, convexHull(extremes)
+}
+
+// convexHull returns the convex hull of points with the monotone chain
+// algorithm, without collinear points.
+func convexHull(points []image.Point) []image.Point {
+	if len(points) < 3 {
+		return points
+	}
+	sorted := make([]image.Point, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+	cross := func(o, a, b image.Point) int {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+
+	hull := make([]image.Point, 0, 2*len(sorted))
+	for _, p := range sorted {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(sorted) - 2; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// ClusterOverlay draws the footprints on the texture: the masks are
+// tinted with the palette color of their label, the hulls are outlined
+// in the same color and the bounding boxes in white.
+func (d *Data) ClusterOverlay(footprints []ClusterFootprint) *image.RGBA {
+	overlay := image.NewRGBA(d.TextureBounds())
+	if d.img.texture != nil {
+		b := d.img.texture.Bounds()
+		for y := 0; y < d.img.height; y++ {
+			for x := 0; x < d.img.width; x++ {
+				overlay.Set(x, y, d.img.texture.At(b.Min.X+x, b.Min.Y+y))
+			}
+		}
+	}
+
+	maxLabel := 0
+	for _, f := range footprints {
+		if f.Label > maxLabel {
+			maxLabel = f.Label
+		}
+	}
+	palette := NewPalette(Categorical, maxLabel+1)
+	white := color.RGBA{255, 255, 255, 255}
+
+	for _, f := range footprints {
+		c := palette.Color(f.Label)
+		tint := color.RGBA{toByte(c.R), toByte(c.G), toByte(c.B), 255}
+		r := f.Bounds.Intersect(overlay.Rect)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if f.Mask.GrayAt(x, y).Y == 0 {
+					continue
+				}
+				o := overlay.RGBAAt(x, y)
+				overlay.SetRGBA(x, y, color.RGBA{
+					uint8((uint16(o.R) + uint16(tint.R)) / 2),
+					uint8((uint16(o.G) + uint16(tint.G)) / 2),
+					uint8((uint16(o.B) + uint16(tint.B)) / 2),
+					255,
+				})
+			}
+		}
+
+		for i, p := range f.Hull {
+			drawLine(overlay, p, f.Hull[(i+1)%len(f.Hull)], tint)
+		}
+		box := f.Bounds
+		corners := []image.Point{
+			box.Min,
+			{box.Max.X - 1, box.Min.Y},
+			box.Max.Sub(image.Pt(1, 1)),
+			{box.Min.X, box.Max.Y - 1},
+		}
+		for i, p := range corners {
+			drawLine(overlay, p, corners[(i+1)%len(corners)], white)
+		}
+	}
+	return overlay
+}
+
+// drawLine draws a line from a to b.
+func drawLine(img *image.RGBA, a, b image.Point, c color.RGBA) {
+	linePixels(a, b, func(x, y int) {
+		if image.Pt(x, y).In(img.Rect) {
+			img.SetRGBA(x, y, c)
+		}
+	})
+}
+
+// TextureBounds returns the pixel rectangle of the texture.
+func (d *Data) TextureBounds() image.Rectangle {
+	return image.Rect(0, 0, d.img.width, d.img.height)
+}
@@ -0,0 +1,535 @@
+package edgedetection
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// GltfLoader reads glTF 2.0 files, both as JSON with embedded
+// base64 buffers and as binary .glb files.
+// Node transformations are applied to the positions and normals,
+// the texture coordinates of TEXCOORD_0 are converted to the OBJ
+// convention with the origin in the lower left corner and the
+// first base color texture is stored in Mesh.Texture.
+// External buffer and image files are not supported.
+type GltfLoader struct{}
+
+const (
+	glbMagic     = 0x46546c67
+	glbChunkJSON = 0x4e4f534a
+	glbChunkBIN  = 0x004e4942
+)
+
+type gltfDocument struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes []struct {
+		Name        string    `json:"name"`
+		Mesh        *int      `json:"mesh"`
+		Children    []int     `json:"children"`
+		Matrix      []float64 `json:"matrix"`
+		Translation []float64 `json:"translation"`
+		Rotation    []float64 `json:"rotation"`
+		Scale       []float64 `json:"scale"`
+	} `json:"nodes"`
+	Meshes []struct {
+		Name       string          `json:"name"`
+		Primitives []gltfPrimitive `json:"primitives"`
+	} `json:"meshes"`
+	Accessors []struct {
+		BufferView    *int        `json:"bufferView"`
+		ByteOffset    int         `json:"byteOffset"`
+		ComponentType int         `json:"componentType"`
+		Normalized    bool        `json:"normalized"`
+		Count         int         `json:"count"`
+		Type          string      `json:"type"`
+		Sparse        interface{} `json:"sparse"`
+	} `json:"accessors"`
+	BufferViews []struct {
+		Buffer     int `json:"buffer"`
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+		ByteStride int `json:"byteStride"`
+	} `json:"bufferViews"`
+	Buffers []struct {
+		URI        string `json:"uri"`
+		ByteLength int    `json:"byteLength"`
+	} `json:"buffers"`
+	Materials []struct {
+		Name                 string `json:"name"`
+		PbrMetallicRoughness struct {
+			BaseColorTexture *struct {
+				Index int `json:"index"`
+			} `json:"baseColorTexture"`
+		} `json:"pbrMetallicRoughness"`
+	} `json:"materials"`
+	Textures []struct {
+		Source *int `json:"source"`
+	} `json:"textures"`
+	Images []struct {
+		URI        string `json:"uri"`
+		BufferView *int   `json:"bufferView"`
+	} `json:"images"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type gltfReader struct {
+	doc     gltfDocument
+	buffers [][]byte
+	mesh    *Mesh
+}
+
+// Load ...
+func (GltfLoader) Load(r io.Reader) (*Mesh, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var bin []byte
+	if len(content) >= 4 && binary.LittleEndian.Uint32(content) == glbMagic {
+		content, bin, err = readGlbChunks(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g := &gltfReader{mesh: &Mesh{}}
+	err = json.Unmarshal(content, &g.doc)
+	if err != nil {
+		return nil, fmt.Errorf("gltf: %v", err)
+	}
+
+	err = g.loadBuffers(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.loadScene()
+	if err != nil {
+		return nil, err
+	}
+	return g.mesh, g.mesh.checkIndexes()
+}
+
+// readGlbChunks splits a binary glTF file into its JSON and BIN chunk.
+func readGlbChunks(content []byte) ([]byte, []byte, error) {
+	if len(content) < 12 {
+		return nil, nil, fmt.Errorf("gltf: glb header is too short")
+	}
+	if version := binary.LittleEndian.Uint32(content[4:]); version != 2 {
+		return nil, nil, fmt.Errorf("gltf: unsupported glb version %d", version)
+	}
+
+	var jsonChunk, binChunk []byte
+	for offset := 12; offset+8 <= len(content); {
+		length := int(binary.LittleEndian.Uint32(content[offset:]))
+		kind := binary.LittleEndian.Uint32(content[offset+4:])
+		offset += 8
+		if length < 0 || offset+length > len(content) {
+			return nil, nil, fmt.Errorf("gltf: glb chunk exceeds the file")
+		}
+
+		switch kind {
+		case glbChunkJSON:
+			jsonChunk = content[offset : offset+length]
+		case glbChunkBIN:
+			binChunk = content[offset : offset+length]
+		}
+		offset += length
+	}
+
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("gltf: glb has no JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
+
+func (g *gltfReader) loadBuffers(bin []byte) error {
+	g.buffers = make([][]byte, len(g.doc.Buffers))
+	for i, b := range g.doc.Buffers {
+		switch {
+		case b.URI == "" && i == 0 && bin != nil:
+			g.buffers[i] = bin
+		case b.URI != "":
+			data, err := decodeDataURI(b.URI)
+			if err != nil {
+				return fmt.Errorf("gltf: buffer %d: %v", i, err)
+			}
+			g.buffers[i] = data
+		default:
+			return fmt.Errorf("gltf: buffer %d has no data", i)
+		}
+		if len(g.buffers[i]) < b.ByteLength {
+			return fmt.Errorf("gltf: buffer %d is shorter than its byteLength", i)
+		}
+	}
+	return nil
+}
+
+func decodeDataURI(uri string) ([]byte, error) {
+	if !strings.HasPrefix(uri, "data:") {
+		return nil, fmt.Errorf("external file %q is not supported", uri)
+	}
+	i := strings.Index(uri, ";base64,")
+	if i < 0 {
+		return nil, fmt.Errorf("data uri is not base64 encoded")
+	}
+	return base64.StdEncoding.DecodeString(uri[i+len(";base64,"):])
+}
+
+// loadScene adds the meshes of the default scene, or of all
+// nodes if the file has no scenes.
+func (g *gltfReader) loadScene() error {
+	if len(g.doc.Scenes) == 0 {
+		for i := range g.doc.Meshes {
+			err := g.addMesh(i, "", mgl64.Ident4())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	scene := 0
+	if g.doc.Scene != nil {
+		scene = *g.doc.Scene
+	}
+	if scene < 0 || scene >= len(g.doc.Scenes) {
+		return fmt.Errorf("gltf: scene %d does not exist", scene)
+	}
+
+	for _, n := range g.doc.Scenes[scene].Nodes {
+		err := g.addNode(n, mgl64.Ident4(), 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *gltfReader) addNode(n int, parent mgl64.Mat4, depth int) error {
+	if n < 0 || n >= len(g.doc.Nodes) {
+		return fmt.Errorf("gltf: node %d does not exist", n)
+	}
+	if depth > len(g.doc.Nodes) {
+		return fmt.Errorf("gltf: node hierarchy has a cycle")
+	}
+	node := g.doc.Nodes[n]
+
+	local := mgl64.Ident4()
+	switch {
+	case len(node.Matrix) == 16:
+		copy(local[:], node.Matrix)
+	default:
+		if len(node.Translation) == 3 {
+			local = local.Mul4(mgl64.Translate3D(node.Translation[0], node.Translation[1], node.Translation[2]))
+		}
+		if len(node.Rotation) == 4 {
+			q := mgl64.Quat{W: node.Rotation[3], V: mgl64.Vec3{node.Rotation[0], node.Rotation[1], node.Rotation[2]}}
+			local = local.Mul4(q.Mat4())
+		}
+		if len(node.Scale) == 3 {
+			local = local.Mul4(mgl64.Scale3D(node.Scale[0], node.Scale[1], node.Scale[2]))
+		}
+	}
+	transform := parent.Mul4(local)
+
+	if node.Mesh != nil {
+		err := g.addMesh(*node.Mesh, node.Name, transform)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range node.Children {
+		err := g.addNode(c, transform, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *gltfReader) addMesh(m int, object string, transform mgl64.Mat4) error {
+	if m < 0 || m >= len(g.doc.Meshes) {
+		return fmt.Errorf("gltf: mesh %d does not exist", m)
+	}
+
+	normalTransform := transform.Mat3().Inv().Transpose()
+	for p, primitive := range g.doc.Meshes[m].Primitives {
+		err := g.addPrimitive(primitive, object, g.doc.Meshes[m].Name, transform, normalTransform)
+		if err != nil {
+			return fmt.Errorf("gltf: mesh %d primitive %d: %v", m, p, err)
+		}
+	}
+	return nil
+}
+
+func (g *gltfReader) addPrimitive(p gltfPrimitive, object, name string, transform mgl64.Mat4, normalTransform mgl64.Mat3) error {
+	mode := 4
+	if p.Mode != nil {
+		mode = *p.Mode
+	}
+	// points and lines have no faces
+	if mode < 4 {
+		return nil
+	}
+
+	position, ok := p.Attributes["POSITION"]
+	if !ok {
+		return fmt.Errorf("no POSITION attribute")
+	}
+	positions, err := g.accessor(position)
+	if err != nil {
+		return err
+	}
+
+	base := len(g.mesh.Positions)
+	for _, v := range positions {
+		g.mesh.Positions = append(g.mesh.Positions, transform.Mul4x1(mgl64.Vec4{v[0], v[1], v[2], 1}).Vec3())
+	}
+
+	var uvs, normals []int
+	if a, ok := p.Attributes["TEXCOORD_0"]; ok {
+		values, err := g.accessor(a)
+		if err != nil {
+			return err
+		}
+		uvs = g.appendUVs(values)
+	}
+	if a, ok := p.Attributes["NORMAL"]; ok {
+		values, err := g.accessor(a)
+		if err != nil {
+			return err
+		}
+		normals = g.appendNormals(values, normalTransform)
+	}
+	if (uvs != nil && len(uvs) != len(positions)) || (normals != nil && len(normals) != len(positions)) {
+		return fmt.Errorf("attributes have different counts")
+	}
+
+	indexes := make([]int, len(positions))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	if p.Indices != nil {
+		values, err := g.accessor(*p.Indices)
+		if err != nil {
+			return err
+		}
+		indexes = make([]int, len(values))
+		for i, v := range values {
+			indexes[i] = int(v[0])
+		}
+	}
+
+	group := Group{Object: object, Name: name}
+	if p.Material != nil && *p.Material >= 0 && *p.Material < len(g.doc.Materials) {
+		group.Material = g.doc.Materials[*p.Material].Name
+		err = g.loadTexture(*p.Material)
+		if err != nil {
+			return err
+		}
+	}
+	g.mesh.Groups = append(g.mesh.Groups, group)
+
+	for _, t := range triangles(indexes, mode) {
+		corners := make([]int, 3)
+		for i, index := range t {
+			if index < 0 || index >= len(positions) {
+				return fmt.Errorf("index %d is out of range", index)
+			}
+			corners[i] = base + index
+		}
+		g.mesh.addPolygon(corners, offsetIndexes(uvs, t), offsetIndexes(normals, t), len(g.mesh.Groups)-1)
+	}
+	return nil
+}
+
+func (g *gltfReader) appendUVs(values [][]float64) []int {
+	indexes := make([]int, len(values))
+	for i, v := range values {
+		g.mesh.UVs = append(g.mesh.UVs, mgl64.Vec2{v[0], 1 - v[1]})
+		indexes[i] = len(g.mesh.UVs) - 1
+	}
+	return indexes
+}
+
+func (g *gltfReader) appendNormals(values [][]float64, normalTransform mgl64.Mat3) []int {
+	indexes := make([]int, len(values))
+	for i, v := range values {
+		n := normalTransform.Mul3x1(mgl64.Vec3{v[0], v[1], v[2]})
+		if n.Len() > 0 {
+			n = n.Normalize()
+		}
+		g.mesh.Normals = append(g.mesh.Normals, n)
+		indexes[i] = len(g.mesh.Normals) - 1
+	}
+	return indexes
+}
+
+// offsetIndexes maps the vertex indexes of a triangle to attribute indexes.
+func offsetIndexes(attribute []int, triangle [3]int) []int {
+	if attribute == nil {
+		return nil
+	}
+	return []int{attribute[triangle[0]], attribute[triangle[1]], attribute[triangle[2]]}
+}
+
+// triangles splits triangle lists (4), strips (5) and fans (6).
+func triangles(indexes []int, mode int) [][3]int {
+	var result [][3]int
+	switch mode {
+	case 4:
+		for i := 0; i+2 < len(indexes); i += 3 {
+			result = append(result, [3]int{indexes[i], indexes[i+1], indexes[i+2]})
+		}
+	case 5:
+		for i := 0; i+2 < len(indexes); i++ {
+			if i%2 == 0 {
+				result = append(result, [3]int{indexes[i], indexes[i+1], indexes[i+2]})
+			} else {
+				result = append(result, [3]int{indexes[i+1], indexes[i], indexes[i+2]})
+			}
+		}
+	case 6:
+		for i := 1; i+1 < len(indexes); i++ {
+			result = append(result, [3]int{indexes[0], indexes[i], indexes[i+1]})
+		}
+	}
+	return result
+}
+
+// loadTexture stores the base color image of the first textured material.
+func (g *gltfReader) loadTexture(material int) error {
+	texture := g.doc.Materials[material].PbrMetallicRoughness.BaseColorTexture
+	if g.mesh.Texture != nil || texture == nil {
+		return nil
+	}
+	if texture.Index < 0 || texture.Index >= len(g.doc.Textures) || g.doc.Textures[texture.Index].Source == nil {
+		return fmt.Errorf("texture %d does not exist", texture.Index)
+	}
+	source := *g.doc.Textures[texture.Index].Source
+	if source < 0 || source >= len(g.doc.Images) {
+		return fmt.Errorf("image %d does not exist", source)
+	}
+
+	image := g.doc.Images[source]
+	if image.BufferView != nil {
+		data, _, err := g.bufferView(*image.BufferView)
+		if err != nil {
+			return err
+		}
+		g.mesh.Texture = data
+		return nil
+	}
+
+	data, err := decodeDataURI(image.URI)
+	if err != nil {
+		return fmt.Errorf("image %d: %v", source, err)
+	}
+	g.mesh.Texture = data
+	return nil
+}
+
+// bufferView returns the bytes and the stride of a buffer view.
+func (g *gltfReader) bufferView(v int) ([]byte, int, error) {
+	if v < 0 || v >= len(g.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("buffer view %d does not exist", v)
+	}
+	view := g.doc.BufferViews[v]
+	if view.Buffer < 0 || view.Buffer >= len(g.buffers) {
+		return nil, 0, fmt.Errorf("buffer %d does not exist", view.Buffer)
+	}
+
+	buffer := g.buffers[view.Buffer]
+	end := view.ByteOffset + view.ByteLength
+	if view.ByteOffset < 0 || end > len(buffer) {
+		return nil, 0, fmt.Errorf("buffer view %d exceeds its buffer", v)
+	}
+	return buffer[view.ByteOffset:end], view.ByteStride, nil
+}
+
+// accessor reads all elements of an accessor as float64 values.
+func (g *gltfReader) accessor(a int) ([][]float64, error) {
+	if a < 0 || a >= len(g.doc.Accessors) {
+		return nil, fmt.Errorf("accessor %d does not exist", a)
+	}
+	accessor := g.doc.Accessors[a]
+	if accessor.Sparse != nil {
+		return nil, fmt.Errorf("sparse accessor %d is not supported", a)
+	}
+
+	components := map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}[accessor.Type]
+	size := map[int]int{5120: 1, 5121: 1, 5122: 2, 5123: 2, 5125: 4, 5126: 4}[accessor.ComponentType]
+	if components == 0 || size == 0 {
+		return nil, fmt.Errorf("accessor %d has an unsupported type", a)
+	}
+
+	values := make([][]float64, accessor.Count)
+	for i := range values {
+		values[i] = make([]float64, components)
+	}
+	// accessors without buffer view are initialised with zeros
+	if accessor.BufferView == nil {
+		return values, nil
+	}
+
+	data, stride, err := g.bufferView(*accessor.BufferView)
+	if err != nil {
+		return nil, err
+	}
+	if stride == 0 {
+		stride = components * size
+	}
+	if accessor.Count > 0 && accessor.ByteOffset+(accessor.Count-1)*stride+components*size > len(data) {
+		return nil, fmt.Errorf("accessor %d exceeds its buffer view", a)
+	}
+
+	for i := range values {
+		element := data[accessor.ByteOffset+i*stride:]
+		for c := range values[i] {
+			values[i][c] = gltfComponent(element[c*size:], accessor.ComponentType, accessor.Normalized)
+		}
+	}
+	return values, nil
+}
+
+func gltfComponent(b []byte, componentType int, normalized bool) float64 {
+	var v, max float64
+	switch componentType {
+	case 5120:
+		v, max = float64(int8(b[0])), 127
+	case 5121:
+		v, max = float64(b[0]), 255
+	case 5122:
+		v, max = float64(int16(binary.LittleEndian.Uint16(b))), 32767
+	case 5123:
+		v, max = float64(binary.LittleEndian.Uint16(b)), 65535
+	case 5125:
+		return float64(binary.LittleEndian.Uint32(b))
+	default:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	if normalized {
+		return math.Max(v/max, -1)
+	}
+	return v
+}
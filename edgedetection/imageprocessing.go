@@ -3,148 +3,176 @@ package edgedetection
 import (
 	"fmt"
 	"image"
-	"image/color"
+	_ "image/jpeg"
 	_ "image/png"
 	"io"
 	"math"
-
-	fatih "github.com/fatih/color"
-	"gocv.io/x/gocv"
 )
 
 type ImageCV struct {
-	mat gocv.Mat
+	mat     BackendImage
+	backend ImageBackend
+	// texture is the decoded original image
+	texture image.Image
 
 	rects  []image.Rectangle
 	height int
 	width  int
 	center []int //[0]width [1]hight
+
+	// regions is the row major label mask of the contour polygons,
+	// the index of the region in rects or -1
+	regions []int32
+	// edgeLevel is the lowest pixel value of an edge
+	edgeLevel uint8
+
+	// depth is registered to the texture, nil without depth input
+	depth *DepthMap
 }
 
-func ImageControler(imgReader io.Reader) *ImageCV {
+// ImageControler finds the regions of interest in the texture.
+// If depth is not nil, it is registered once to the texture resolution,
+// its edges are added to the Canny edges and the registered map is
+// kept on the returned image.
+// The stages are set by config, all intermediate images are sent to config.Debug.
+// An image that cannot be decoded or converted is returned as error.
+func ImageControler(imgReader io.Reader, depth *DepthMap, config *DetectionConfig) (*ImageCV, error) {
+
+	sink := config.Debug
+	backend := config.Backend
+	if backend == nil {
+		backend = defaultBackend()
+	}
 
 	// Read Original image
-	org := readImg(imgReader)
-	org.showImg("Orginal")
-	// Blur image (sigmaX, sigmaY, kernel size)
-	blured := org.gauSSianBlur(0, 0, 7)
-	// Auto canny
-	edge := blured.canny(0.5)
-	// Dilatation
-	dilate := edge.dilatation(100)
+	org, err := readImg(imgReader, backend)
+	if err != nil {
+		return nil, err
+	}
+	org.debugImage(sink, "original")
+	if depth != nil {
+		org.depth = depth.Register(org.mat.Cols(), org.mat.Rows())
+	}
+	var edge *ImageCV
+	if len(config.EdgeDetectors) > 0 {
+		// Multi-scale edge confidence
+		edge = org.edgeConfidence(config)
+	} else {
+		// Blur image (sigmaX, sigmaY, kernel size)
+		blured := org.gauSSianBlur(0, 0, config.BlurKernel)
+		// Auto canny
+		edge = blured.canny(config.CannySigma)
+		if org.depth != nil {
+			edge.addEdges(org.depth.Edges(config.DepthEdgeJump))
+		}
+	}
+	edge.debugImage(sink, "edges")
+	// Dilatation, the maximum confidence for an edge confidence map
+	dilate := edge.dilatation(config.DilationKernel)
+	dilate.debugImage(sink, "dilatation")
+	dilate.edgeLevel = edgeLevel(config.EdgeThreshold)
 	// Find contours for dilate
-	dilate.findContours()
-	// Show BB
-	// dilate.loopContours()
-
+	contours := dilate.findContours(config.MinContourArea)
 	// NonMaximaSuppression for BB
-	// dilate.nonMaxSuppression(10000)
+	regionOf := dilate.nonMaxSuppression(contours, config)
+	// Label mask of the contour polygons
+	dilate.fillRegions(contours, regionOf)
+	// Show BB
+	// dilate.loopContours(sink)
 
 	// Calculate image information's
 	dilate.getcenter()
-	return dilate
+	return dilate, nil
+}
+
+// with returns a new ImageCV of the same backend.
+func (i *ImageCV) with(mat BackendImage) *ImageCV {
+	return &ImageCV{mat: mat, backend: i.backend, texture: i.texture, depth: i.depth}
 }
 
-func (i *ImageCV) findContours() {
+// findContours sets the bounding boxes of the contours of the pixels
+// of at least edgeLevel with more than minArea pixels and returns
+// these contours.
+func (i *ImageCV) findContours(minArea float64) []Contour {
 	rects := make([]image.Rectangle, 0)
-	contours := gocv.FindContours(i.mat, 0, 2)
+	filtered := make([]Contour, 0)
+	contours := i.backend.Contours(i.threshold(i.edgeLevel).mat)
 
 	for _, c := range contours {
-		area := gocv.ContourArea(c)
-		// Filter bb with less then 5000 image Points
-		if area > 5000 {
-			rect := gocv.BoundingRect(c)
-			rects = append(rects, rect)
+		// Filter bb with less then minArea image Points
+		if c.Area > minArea {
+			rects = append(rects, c.Bounds)
+			filtered = append(filtered, c)
 		}
 	}
 	i.rects = rects
+	return filtered
 }
 
-func (i *ImageCV) loopContours() {
+func (i *ImageCV) loopContours(sink DebugSink) {
 
 	input := i.mat.Clone()
 	for _, r := range i.rects {
-		gocv.Rectangle(&input, r, color.RGBA{255, 255, 255, 255}, 3)
+		i.backend.Rectangle(input, r, 3)
 	}
-	window := gocv.NewWindow("Contours")
-	window.IMShow(input)
-	window.ResizeWindow(1024, 1024)
-	window.WaitKey(0)
-
+	i.with(input).debugImage(sink, "contours")
 }
 
 func (i *ImageCV) canny(sigma float64) *ImageCV {
 
-	e := gocv.NewMat()
-	img := i.mat
 	mean := i.mat.Mean()
-	lower := math.Max(0, (1.0-sigma)*mean.Val1)
-	upper := math.Min(255, (1.0+sigma)*mean.Val1)
-	gocv.Canny(img, &e, float32(lower), float32(upper))
+	lower := math.Max(0, (1.0-sigma)*mean)
+	upper := math.Min(255, (1.0+sigma)*mean)
 
-	return &ImageCV{mat: e}
+	return i.with(i.backend.Canny(i.mat, lower, upper))
 }
 
-func (i *ImageCV) gauSSianBlur(sigmaX float64, sigmaY float64, ksize int) *ImageCV {
-
-	src := i.mat
-	dst := gocv.NewMat()
-	kernel := image.Point{ksize, ksize}
-	gocv.GaussianBlur(src, &dst, kernel, sigmaX, sigmaY, 1)
-
-	return &ImageCV{mat: dst}
-}
-
-func initKernel(dim int) gocv.Mat {
-	kernel := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(dim, dim))
-	return kernel
+// addEdges sets every marked pixel of a row major mask to 255.
+func (i *ImageCV) addEdges(mask []bool) {
+	cols := i.mat.Cols()
+	for p, edge := range mask {
+		if edge {
+			i.mat.Set(p/cols, p%cols, 255)
+		}
+	}
 }
 
-func (i *ImageCV) dilatation(kernelsize int) *ImageCV {
+func (i *ImageCV) gauSSianBlur(sigmaX float64, sigmaY float64, ksize int) *ImageCV {
 
-	kernel := initKernel(kernelsize)
-	dilate := gocv.NewMat()
-	gocv.Dilate(i.mat, &dilate, kernel)
-	return &ImageCV{mat: dilate}
+	return i.with(i.backend.GaussianBlur(i.mat, ksize, sigmaX, sigmaY))
 }
 
-func (icv *ImageCV) showImg(windownName string) {
+func (i *ImageCV) dilatation(kernelsize int) *ImageCV {
 
-	window := gocv.NewWindow(windownName)
-	window.IMShow(icv.mat)
-	window.ResizeWindow(1024, 1024)
-	window.WaitKey(0)
+	return i.with(i.backend.Dilate(i.mat, kernelsize))
 }
 
-func readImg(imgReader io.Reader) *ImageCV {
+func readImg(imgReader io.Reader, backend ImageBackend) (*ImageCV, error) {
 
 	img, _, err := image.Decode(imgReader)
 	if err != nil {
-		fatih.Red("Can't decode image", err)
+		return nil, fmt.Errorf("can't decode image: %v", err)
 	}
-	mat, err := gocv.ImageToMatRGB(img)
-	gocv.CvtColor(mat, &mat, gocv.ColorBGRToGray)
-	return &ImageCV{mat: mat}
+	mat, err := backend.FromImage(img)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert image: %v", err)
+	}
+	return &ImageCV{mat: mat, backend: backend, texture: img}, nil
 }
 
 func (i *ImageCV) resizeImage(fx float64, fy float64) *ImageCV {
 
-	dst := gocv.NewMat()
-	gocv.Resize(i.mat, &dst, image.Point{}, fx, fy, 0)
-
-	return &ImageCV{mat: dst}
+	return i.with(i.backend.Resize(i.mat, fx, fy))
 }
 
 func (i *ImageCV) crop(left, top, right, bottom int) *ImageCV {
 	fmt.Println("Cropping", left, top, right, bottom)
-	croppedMat := i.mat.Region(image.Rect(left, top, right, bottom))
-	return &ImageCV{mat: croppedMat}
+	return i.with(i.backend.Crop(i.mat, image.Rect(left, top, right, bottom)))
 }
 
 func (i *ImageCV) getcenter() {
 
-	dim := i.mat.Size()
-	i.height = dim[0]
-	i.width = dim[1]
+	i.height = i.mat.Rows()
+	i.width = i.mat.Cols()
 	i.center = []int{i.width / 2, i.height / 2}
 }
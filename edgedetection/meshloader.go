@@ -0,0 +1,174 @@
+package edgedetection
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// MeshLoader reads a mesh in one file format.
+// Faces without texture coordinates are kept, but they are
+// skipped when the pixels of the texture are looked up.
+type MeshLoader interface {
+	Load(r io.Reader) (*Mesh, error)
+}
+
+// ObjLoader reads Wavefront OBJ files.
+type ObjLoader struct{}
+
+// Load ...
+func (ObjLoader) Load(r io.Reader) (*Mesh, error) {
+	return ReadObjFile(r)
+}
+
+// LoaderForFile returns the loader for the extension of a file name.
+func LoaderForFile(name string) (MeshLoader, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".obj":
+		return ObjLoader{}, nil
+	case ".ply":
+		return PlyLoader{}, nil
+	case ".stl":
+		return StlLoader{}, nil
+	case ".gltf", ".glb":
+		return GltfLoader{}, nil
+	}
+	return nil, fmt.Errorf("unknown mesh file extension %q", filepath.Ext(name))
+}
+
+// ReadMesh detects the format of a mesh by its first bytes and reads it.
+// A binary STL is recognised by its triangle count if the reader reports
+// its size (files, bytes.Reader, ...), otherwise every input that is not
+// UTF-8 text is read as binary STL. Use LoaderForFile if the file name is known.
+func ReadMesh(r io.Reader) (*Mesh, error) {
+	size := readerSize(r)
+	reader := bufio.NewReaderSize(r, 1024)
+	// Peek returns io.EOF for files smaller than the buffer
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	return detectLoader(head, size).Load(reader)
+}
+
+// readerSize returns the number of bytes left in r, -1 if r does not report it.
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+// detectLoader picks the loader for the first bytes of a mesh,
+// size is the size of the whole input or -1 if it is unknown.
+func detectLoader(head []byte, size int64) MeshLoader {
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	switch {
+	case isBinaryStl(head, size):
+		return StlLoader{}
+	case bytes.HasPrefix(head, []byte("ply\n")) || bytes.HasPrefix(head, []byte("ply\r\n")):
+		return PlyLoader{}
+	case bytes.HasPrefix(head, []byte("glTF")) || bytes.HasPrefix(trimmed, []byte("{")):
+		return GltfLoader{}
+	case isAsciiStl(head):
+		return StlLoader{}
+	case isText(head):
+		return ObjLoader{}
+	}
+	return StlLoader{}
+}
+
+// isBinaryStl reports if the triangle count of a binary STL header
+// matches the size of the input, 84 bytes plus 50 bytes per triangle.
+func isBinaryStl(head []byte, size int64) bool {
+	if size < 84 || len(head) < 84 {
+		return false
+	}
+	count := int64(binary.LittleEndian.Uint32(head[80:84]))
+	return 84+50*count == size
+}
+
+// isText reports if the bytes are UTF-8 without control characters
+// other than whitespace. A rune cut off at the end is accepted.
+func isText(head []byte) bool {
+	for len(head) > 0 {
+		r, n := utf8.DecodeRune(head)
+		if r == utf8.RuneError && n <= 1 {
+			return len(head) < utf8.UTFMax && !utf8.FullRune(head)
+		}
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' || r == 0x7f {
+			return false
+		}
+		head = head[n:]
+	}
+	return true
+}
+
+// meshBuilder deduplicates the vertex positions of formats
+// that store them per triangle.
+type meshBuilder struct {
+	mesh    *Mesh
+	indexes map[[3]float64]int
+}
+
+func newMeshBuilder() *meshBuilder {
+	return &meshBuilder{
+		mesh:    &Mesh{Groups: []Group{{}}},
+		indexes: make(map[[3]float64]int),
+	}
+}
+
+func (b *meshBuilder) position(x, y, z float64) int {
+	key := [3]float64{x, y, z}
+	if i, ok := b.indexes[key]; ok {
+		return i
+	}
+	b.mesh.Positions = append(b.mesh.Positions, mgl64.Vec3(key))
+	b.indexes[key] = len(b.mesh.Positions) - 1
+	return len(b.mesh.Positions) - 1
+}
+
+// addPolygon fan triangulates a polygon. uvs and normals may be nil.
+func (m *Mesh) addPolygon(positions, uvs, normals []int, group int) {
+	polygon := 0
+	if len(m.Faces) > 0 {
+		polygon = m.Faces[len(m.Faces)-1].Polygon + 1
+	}
+
+	corner := func(indexes []int, i int) int {
+		if indexes == nil {
+			return -1
+		}
+		return indexes[i]
+	}
+
+	for i := 1; i < len(positions)-1; i++ {
+		face := Face{Group: group, Polygon: polygon}
+		for j, k := range [3]int{0, i, i + 1} {
+			face.Position[j] = positions[k]
+			face.UV[j] = corner(uvs, k)
+			face.Normal[j] = corner(normals, k)
+		}
+		m.Faces = append(m.Faces, face)
+	}
+}
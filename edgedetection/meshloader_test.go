@@ -0,0 +1,236 @@
+package edgedetection
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// square is a unit square in the z=0 plane as two triangles,
+// every triangle is a normal followed by three vertices.
+var square = [][12]float32{
+	{0, 0, 1, 0, 0, 0, 1, 0, 0, 1, 1, 0},
+	{0, 0, 1, 0, 0, 0, 1, 1, 0, 0, 1, 0},
+}
+
+const asciiStl = `solid square
+facet normal 0 0 1
+  outer loop
+    vertex 0 0 0
+    vertex 1 0 0
+    vertex 1 1 0
+  endloop
+endfacet
+facet normal 0 0 1
+  outer loop
+    vertex 0 0 0
+    vertex 1 1 0
+    vertex 0 1 0
+  endloop
+endfacet
+endsolid square
+`
+
+func binaryStl(header string, triangles [][12]float32) []byte {
+	var b bytes.Buffer
+	h := make([]byte, 80)
+	copy(h, header)
+	b.Write(h)
+	binary.Write(&b, binary.LittleEndian, uint32(len(triangles)))
+	for _, t := range triangles {
+		binary.Write(&b, binary.LittleEndian, t)
+		b.Write([]byte{0, 0})
+	}
+	return b.Bytes()
+}
+
+const plyHeader = `ply
+format %s 1.0
+comment a unit square
+element vertex 4
+property float x
+property float y
+property float z
+property float u
+property float v
+element face 1
+property list uchar int vertex_indices
+end_header
+`
+
+var plyVertices = [][5]float32{{0, 0, 0, 0, 0}, {1, 0, 0, 1, 0}, {1, 1, 0, 1, 1}, {0, 1, 0, 0, 1}}
+
+func asciiPly() []byte {
+	var b bytes.Buffer
+	b.WriteString(replaceFormat("ascii"))
+	for _, v := range plyVertices {
+		for i, f := range v {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+		}
+		b.WriteString("\r\n")
+	}
+	b.WriteString("4 0 1 2 3\n")
+	return b.Bytes()
+}
+
+func binaryPly(format string, order binary.ByteOrder) []byte {
+	var b bytes.Buffer
+	b.WriteString(replaceFormat(format))
+	for _, v := range plyVertices {
+		binary.Write(&b, order, v)
+	}
+	b.WriteByte(4)
+	binary.Write(&b, order, []int32{0, 1, 2, 3})
+	return b.Bytes()
+}
+
+func replaceFormat(format string) string {
+	return strings.Replace(plyHeader, "%s", format, 1)
+}
+
+// texturedGlb is a binary glTF with one triangle, texture
+// coordinates, indices and an embedded base color image.
+func texturedGlb(texture []byte) []byte {
+	var bin bytes.Buffer
+	binary.Write(&bin, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 0, 1, 0})
+	binary.Write(&bin, binary.LittleEndian, []float32{0, 0, 1, 0, 0, 0.25})
+	binary.Write(&bin, binary.LittleEndian, []uint16{0, 1, 2, 0})
+	bin.Write(texture)
+	for bin.Len()%4 != 0 {
+		bin.WriteByte(0)
+	}
+
+	doc := `{"asset":{"version":"2.0"},"scene":0,"scenes":[{"nodes":[0]}],
+"nodes":[{"name":"part","mesh":0,"translation":[0,0,2]}],
+"meshes":[{"name":"triangle","primitives":[{"attributes":{"POSITION":0,"TEXCOORD_0":1},"indices":2,"material":0}]}],
+"materials":[{"name":"paint","pbrMetallicRoughness":{"baseColorTexture":{"index":0}}}],
+"textures":[{"source":0}],
+"images":[{"bufferView":3,"mimeType":"image/png"}],
+"accessors":[
+{"bufferView":0,"componentType":5126,"count":3,"type":"VEC3"},
+{"bufferView":1,"componentType":5126,"count":3,"type":"VEC2"},
+{"bufferView":2,"componentType":5123,"count":3,"type":"SCALAR"}],
+"bufferViews":[
+{"buffer":0,"byteOffset":0,"byteLength":36},
+{"buffer":0,"byteOffset":36,"byteLength":24},
+{"buffer":0,"byteOffset":60,"byteLength":6},
+{"buffer":0,"byteOffset":68,"byteLength":` + strconv.Itoa(len(texture)) + `}],
+"buffers":[{"byteLength":` + strconv.Itoa(bin.Len()) + `}]}`
+	for len(doc)%4 != 0 {
+		doc += " "
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, []uint32{glbMagic, 2, uint32(12 + 8 + len(doc) + 8 + bin.Len())})
+	binary.Write(&b, binary.LittleEndian, []uint32{uint32(len(doc)), glbChunkJSON})
+	b.WriteString(doc)
+	binary.Write(&b, binary.LittleEndian, []uint32{uint32(bin.Len()), glbChunkBIN})
+	b.Write(bin.Bytes())
+	return b.Bytes()
+}
+
+func TestReadMesh(t *testing.T) {
+	texture := []byte("\x89PNG not really")
+	squareFaces := []Face{
+		{Position: [3]int{0, 1, 2}, UV: [3]int{-1, -1, -1}, Normal: [3]int{0, 0, 0}},
+		{Position: [3]int{0, 2, 3}, UV: [3]int{-1, -1, -1}, Normal: [3]int{1, 1, 1}, Polygon: 1},
+	}
+	plyFaces := []Face{
+		{Position: [3]int{0, 1, 2}, UV: [3]int{0, 1, 2}, Normal: [3]int{-1, -1, -1}},
+		{Position: [3]int{0, 2, 3}, UV: [3]int{0, 2, 3}, Normal: [3]int{-1, -1, -1}},
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		loader  MeshLoader
+		faces   []Face
+		uvs     int
+		texture []byte
+	}{
+		{"ascii stl", []byte(asciiStl), StlLoader{}, squareFaces, 0, nil},
+		{"binary stl", binaryStl("exported by a scanner", square), StlLoader{}, squareFaces, 0, nil},
+		{"binary stl with solid header", binaryStl("solid square", square), StlLoader{}, squareFaces, 0, nil},
+		{"binary stl with solid and facet header", binaryStl("solid square, 2 facets\n", square), StlLoader{}, squareFaces, 0, nil},
+		{"ascii ply", asciiPly(), PlyLoader{}, plyFaces, 4, nil},
+		{"binary little endian ply", binaryPly("binary_little_endian", binary.LittleEndian), PlyLoader{}, plyFaces, 4, nil},
+		{"binary big endian ply", binaryPly("binary_big_endian", binary.BigEndian), PlyLoader{}, plyFaces, 4, nil},
+		{"glb with texture", texturedGlb(texture), GltfLoader{}, []Face{{Position: [3]int{0, 1, 2}, UV: [3]int{0, 1, 2}, Normal: [3]int{-1, -1, -1}}}, 3, texture},
+	}
+
+	for _, test := range tests {
+		if loader := detectLoader(test.data, int64(len(test.data))); reflect.TypeOf(loader) != reflect.TypeOf(test.loader) {
+			t.Errorf("%s: detected %T, want %T", test.name, loader, test.loader)
+		}
+
+		// a reader without size and the loader itself must agree
+		for _, r := range []io.Reader{bytes.NewReader(test.data), io.MultiReader(bytes.NewReader(test.data))} {
+			mesh, err := ReadMesh(r)
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				continue
+			}
+			if !reflect.DeepEqual(mesh.Faces, test.faces) {
+				t.Errorf("%s: faces %+v, want %+v", test.name, mesh.Faces, test.faces)
+			}
+			if len(mesh.UVs) != test.uvs {
+				t.Errorf("%s: %d UVs, want %d", test.name, len(mesh.UVs), test.uvs)
+			}
+			if !bytes.Equal(mesh.Texture, test.texture) {
+				t.Errorf("%s: texture %q, want %q", test.name, mesh.Texture, test.texture)
+			}
+		}
+	}
+}
+
+func TestReadMeshValues(t *testing.T) {
+	stl, err := ReadMesh(bytes.NewReader(binaryStl("solid square", square)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stl.Positions) != 4 || stl.Positions[2] != [3]float64{1, 1, 0} || stl.Normals[0] != [3]float64{0, 0, 1} {
+		t.Errorf("binary stl: positions %v normals %v", stl.Positions, stl.Normals)
+	}
+
+	ply, err := ReadMesh(bytes.NewReader(binaryPly("binary_big_endian", binary.BigEndian)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ply.Positions[2] != [3]float64{1, 1, 0} || ply.UVs[3] != [2]float64{0, 1} {
+		t.Errorf("big endian ply: positions %v UVs %v", ply.Positions, ply.UVs)
+	}
+
+	glb, err := ReadMesh(bytes.NewReader(texturedGlb([]byte("png"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the node translation moves the triangle and V is flipped
+	if glb.Positions[1] != [3]float64{1, 0, 2} || math.Abs(glb.UVs[2][1]-0.75) > 1e-6 {
+		t.Errorf("glb: positions %v UVs %v", glb.Positions, glb.UVs)
+	}
+	if glb.Groups[0] != (Group{Object: "part", Name: "triangle", Material: "paint"}) {
+		t.Errorf("glb: group %+v", glb.Groups[0])
+	}
+}
+
+func TestReadMeshErrors(t *testing.T) {
+	truncated := binaryStl("exported", square)
+	for name, data := range map[string][]byte{
+		"truncated binary stl":   truncated[:len(truncated)-10],
+		"ply index out of range": bytes.Replace(asciiPly(), []byte("4 0 1 2 3"), []byte("4 0 1 2 4"), 1),
+		"ply without end_header": []byte("ply\nformat ascii 1.0\nelement vertex 1\n"),
+		"glb without JSON chunk": texturedGlb(nil)[:12],
+	} {
+		if _, err := ReadMesh(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -1,86 +1,173 @@
 package edgedetection
 
-import "image"
-
-func (i *ImageCV) nonMaxSuppression(overlapThreshold float64) {
-	if len(i.rects) == 0 {
-		return
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// OverlapCriterion selects how the overlap of two boxes is measured.
+type OverlapCriterion int
+
+const (
+	// IoU is the intersection over the union of both boxes.
+	IoU OverlapCriterion = iota
+	// IoMin is the intersection over the smaller box,
+	// a box inside another box overlaps it completely.
+	IoMin
+)
+
+// ParseOverlapCriterion returns the criterion named "iou" or "iomin".
+func ParseOverlapCriterion(name string) (OverlapCriterion, error) {
+	switch name {
+	case "iou":
+		return IoU, nil
+	case "iomin":
+		return IoMin, nil
 	}
+	return IoU, fmt.Errorf("unknown overlap criterion %q", name)
+}
 
-	// Sort BB by bottom- right y- coordinate
-	i.rects = mergeSort(i.rects)
-
-	bb := make([][]float64, len(i.rects))
-	area := make([]float64, len(i.rects))
+// ScoredBox is a bounding box with the score of its detection,
+// the contour area for contour boxes.
+type ScoredBox struct {
+	Box   image.Rectangle
+	Score float64
+}
 
-	for i, b := range i.rects {
-		bb[i] = []float64{float64(b.Min.X), float64(b.Min.Y), float64(b.Max.X), float64(b.Max.Y)}
-		area[i] = (float64(b.Max.X) - float64(b.Min.X+1)) / (float64(b.Max.Y) - float64(b.Min.Y+1))
+// Overlap returns the overlap of two boxes between 0 and 1.
+func Overlap(a, b image.Rectangle, criterion OverlapCriterion) float64 {
+	intersection := boxArea(a.Intersect(b))
+	if intersection == 0 {
+		return 0
 	}
 
-	suppress := make([]int, 0)
-	for len(bb) > 0 {
-		last := len(bb) - 1
-		var xx1, yy1, xx2, yy2, w, h float64
-		for j := 0; j < last; j++ {
-
-			// find the largest (x, y) coordinates for the start of
-			// the bounding box and the smallest (x, y) coordinates
-			// for the end of the bounding box
-			xx1 = maxValue(bb[last][0], bb[j][0])
-			yy1 = maxValue(bb[last][1], bb[j][1])
-			xx2 = minValue(bb[last][2], bb[j][2])
-			yy2 = minValue(bb[last][3], bb[j][3])
+	var denominator float64
+	switch criterion {
+	case IoMin:
+		denominator = boxArea(a)
+		if area := boxArea(b); area < denominator {
+			denominator = area
+		}
+	default:
+		denominator = boxArea(a) + boxArea(b) - intersection
+	}
+	return intersection / denominator
+}
 
-			// compute the width and height of the bounding box
-			w = maxValue(0, xx2-xx1+1)
-			h = maxValue(0, yy2-yy1+1)
+func boxArea(r image.Rectangle) float64 {
+	if r.Empty() {
+		return 0
+	}
+	return float64(r.Dx()) * float64(r.Dy())
+}
 
-			overlap := (w * h) / area[j]
+// byScore returns the indexes of the boxes by descending score,
+// equal scores by descending area.
+func byScore(boxes []ScoredBox) []int {
+	order := make([]int, len(boxes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := boxes[order[i]], boxes[order[j]]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return boxArea(a.Box) > boxArea(b.Box)
+	})
+	return order
+}
 
-			if overlap > overlapThreshold {
-				contain := containsInt(suppress, j)
-				if !contain {
-					suppress = append(suppress, j)
-				}
+// NonMaxSuppression visits the boxes by descending score and keeps
+// every box that overlaps no kept box by more than threshold.
+// It returns the indexes of the kept boxes by descending score.
+func NonMaxSuppression(boxes []ScoredBox, criterion OverlapCriterion, threshold float64) []int {
+	var kept []int
+	for _, i := range byScore(boxes) {
+		suppressed := false
+		for _, k := range kept {
+			if Overlap(boxes[i].Box, boxes[k].Box, criterion) > threshold {
+				suppressed = true
+				break
 			}
 		}
-		bb = bb[:last]
-	}
-
-	rectNew := make([]image.Rectangle, 0)
-	for k := 0; k < len(i.rects); k++ {
-		if !containsInt(suppress, k) {
-			rectNew = append(rectNew, i.rects[k])
+		if !suppressed {
+			kept = append(kept, i)
 		}
 	}
-	i.rects = rectNew
+	return kept
 }
 
-func containsInt(list []int, i int) bool {
-	for _, v := range list {
-		if v == i {
-			return true
-		}
+// MergeBoxes replaces boxes that overlap by more than threshold by their
+// union with the higher score, until no two boxes overlap by more than
+// threshold. The merged boxes are returned by descending score.
+func MergeBoxes(boxes []ScoredBox, criterion OverlapCriterion, threshold float64) []ScoredBox {
+	merged := make([]ScoredBox, 0, len(boxes))
+	for _, i := range byScore(boxes) {
+		merged = append(merged, boxes[i])
 	}
 
-	return false
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(merged); i++ {
+			for j := i + 1; j < len(merged); j++ {
+				if Overlap(merged[i].Box, merged[j].Box, criterion) <= threshold {
+					continue
+				}
+				// merged is ordered, i has the higher score
+				merged[i].Box = merged[i].Box.Union(merged[j].Box)
+				merged = append(merged[:j], merged[j+1:]...)
+				changed = true
+				j = i
+			}
+		}
+	}
+	return merged
 }
 
-func maxValue(x float64, y float64) float64 {
-
-	if x > y {
-		return x
-	} else {
-		return y
+// nonMaxSuppression reduces the contour boxes with the box filter of the
+// config. It returns the index in rects of the region every contour
+// belongs to, -1 for suppressed contours.
+func (i *ImageCV) nonMaxSuppression(contours []Contour, config *DetectionConfig) []int {
+	regionOf := make([]int, len(contours))
+	for k := range regionOf {
+		regionOf[k] = k
+	}
+	if config.BoxFilter == "" || len(contours) == 0 {
+		return regionOf
 	}
-}
 
-func minValue(x float64, y float64) float64 {
+	boxes := make([]ScoredBox, len(contours))
+	for k, c := range contours {
+		boxes[k] = ScoredBox{Box: c.Bounds, Score: c.Area}
+	}
+	criterion, _ := ParseOverlapCriterion(config.BoxOverlap)
 
-	if x > y {
-		return y
-	} else {
-		return x
+	rects := make([]image.Rectangle, 0, len(boxes))
+	switch config.BoxFilter {
+	case "suppress":
+		for k := range regionOf {
+			regionOf[k] = -1
+		}
+		for _, k := range NonMaxSuppression(boxes, criterion, config.BoxThreshold) {
+			regionOf[k] = len(rects)
+			rects = append(rects, boxes[k].Box)
+		}
+	case "merge":
+		for _, b := range MergeBoxes(boxes, criterion, config.BoxThreshold) {
+			rects = append(rects, b.Box)
+		}
+		// every contour is inside the union it was merged into
+		for k, b := range boxes {
+			for r, rect := range rects {
+				if b.Box.In(rect) {
+					regionOf[k] = r
+					break
+				}
+			}
+		}
 	}
+	i.rects = rects
+	return regionOf
 }
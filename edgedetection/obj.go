@@ -0,0 +1,263 @@
+package edgedetection
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Mesh is a triangulated mesh as read by a MeshLoader.
+type Mesh struct {
+	Positions []mgl64.Vec3
+	UVs       []mgl64.Vec2
+	Normals   []mgl64.Vec3
+	Faces     []Face
+	// Groups holds every combination of object, group and material
+	// that is used by a face
+	Groups []Group
+	// Texture is the encoded texture image of formats
+	// that embed it, nil otherwise
+	Texture []byte
+}
+
+// Face is a triangle of a mesh. Polygons with more than three
+// vertices are split into a fan of triangles.
+// All indexes are zero based, a missing UV or normal index is -1.
+type Face struct {
+	Position [3]int
+	UV       [3]int
+	Normal   [3]int
+	// Group is the index into Mesh.Groups
+	Group int
+	// Polygon is the index of the polygon in the file
+	// the triangle was created from
+	Polygon int
+}
+
+// Group describes the "o", "g" and "usemtl" state of a face.
+type Group struct {
+	Object   string
+	Name     string
+	Material string
+}
+
+// ObjError is returned for invalid lines of an OBJ file.
+type ObjError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ObjError) Error() string {
+	return fmt.Sprintf("obj line %d: %s", e.Line, e.Msg)
+}
+
+// ReadObjFile reads a Wavefront OBJ file line by line.
+// It supports CRLF line endings, any whitespace between the values,
+// line continuations, "v", "v/vt", "v//vn" and "v/vt/vn" face vertices,
+// negative (relative) indexes and polygons with any number of vertices,
+// which are fan triangulated. Unsupported statements are ignored.
+func ReadObjFile(meshReader io.Reader) (*Mesh, error) {
+	mesh := &Mesh{}
+	reader := bufio.NewReader(meshReader)
+
+	current := Group{}
+	group := -1
+	polygon := 0
+	lineNumber := 0
+	for {
+		line, n, err := readObjLine(reader)
+		lineNumber += n
+		if err != nil && err != io.EOF {
+			return nil, &ObjError{Line: lineNumber, Msg: err.Error()}
+		}
+
+		line = stripObjComment(line)
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			perr := mesh.parseStatement(fields, &current, &group, &polygon)
+			if perr != nil {
+				return nil, &ObjError{Line: lineNumber, Msg: perr.Error()}
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return mesh, nil
+}
+
+// stripObjComment removes a comment that starts at the beginning of the
+// line or after whitespace, a "#" inside a name like "mat#1" is kept.
+func stripObjComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// readObjLine reads one logical line, joining lines that end with
+// a backslash. It returns the number of physical lines read.
+func readObjLine(reader *bufio.Reader) (string, int, error) {
+	var builder strings.Builder
+	lines := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			return builder.String(), lines, err
+		}
+		lines++
+
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasSuffix(line, "\\") {
+			builder.WriteString(line[:len(line)-1])
+			builder.WriteByte(' ')
+			if err != nil {
+				return builder.String(), lines, err
+			}
+			continue
+		}
+		builder.WriteString(line)
+		return builder.String(), lines, err
+	}
+}
+
+func (m *Mesh) parseStatement(fields []string, current *Group, group, polygon *int) error {
+	switch fields[0] {
+	case "v":
+		v, err := parseFloats(fields[1:], 3)
+		if err != nil {
+			return err
+		}
+		m.Positions = append(m.Positions, mgl64.Vec3{v[0], v[1], v[2]})
+	case "vt":
+		v, err := parseFloats(fields[1:], 1)
+		if err != nil {
+			return err
+		}
+		uv := mgl64.Vec2{v[0], 0}
+		if len(v) > 1 {
+			uv[1] = v[1]
+		}
+		m.UVs = append(m.UVs, uv)
+	case "vn":
+		v, err := parseFloats(fields[1:], 3)
+		if err != nil {
+			return err
+		}
+		m.Normals = append(m.Normals, mgl64.Vec3{v[0], v[1], v[2]})
+	case "o":
+		current.Object = strings.Join(fields[1:], " ")
+		*group = -1
+	case "g":
+		current.Name = strings.Join(fields[1:], " ")
+		*group = -1
+	case "usemtl":
+		current.Material = strings.Join(fields[1:], " ")
+		*group = -1
+	case "f":
+		if len(fields) < 4 {
+			return fmt.Errorf("face needs at least 3 vertices, got %d", len(fields)-1)
+		}
+		if *group < 0 {
+			m.Groups = append(m.Groups, *current)
+			*group = len(m.Groups) - 1
+		}
+
+		vertices := make([][3]int, len(fields)-1)
+		for i, f := range fields[1:] {
+			v, err := m.parseFaceVertex(f)
+			if err != nil {
+				return err
+			}
+			vertices[i] = v
+		}
+
+		for i := 1; i < len(vertices)-1; i++ {
+			triangle := [3][3]int{vertices[0], vertices[i], vertices[i+1]}
+			face := Face{Group: *group, Polygon: *polygon}
+			for j, v := range triangle {
+				face.Position[j] = v[0]
+				face.UV[j] = v[1]
+				face.Normal[j] = v[2]
+			}
+			m.Faces = append(m.Faces, face)
+		}
+		*polygon++
+	}
+
+	return nil
+}
+
+// parseFaceVertex parses "v", "v/vt", "v//vn" or "v/vt/vn"
+// into zero based position, UV and normal indexes.
+func (m *Mesh) parseFaceVertex(field string) ([3]int, error) {
+	vertex := [3]int{-1, -1, -1}
+	parts := strings.Split(field, "/")
+	if len(parts) > 3 {
+		return vertex, fmt.Errorf("invalid face vertex %q", field)
+	}
+
+	counts := [3]int{len(m.Positions), len(m.UVs), len(m.Normals)}
+	for i, p := range parts {
+		if p == "" {
+			if i == 0 {
+				return vertex, fmt.Errorf("face vertex %q has no position", field)
+			}
+			continue
+		}
+
+		index, err := strconv.Atoi(p)
+		if err != nil {
+			return vertex, fmt.Errorf("invalid index %q", p)
+		}
+		switch {
+		case index > 0:
+			index--
+		case index < 0:
+			index += counts[i]
+		default:
+			return vertex, fmt.Errorf("index 0 is not allowed")
+		}
+		if index < 0 || index >= counts[i] {
+			return vertex, fmt.Errorf("index %s is out of range", p)
+		}
+		vertex[i] = index
+	}
+
+	return vertex, nil
+}
+
+func parseFloats(fields []string, minimum int) ([]float64, error) {
+	if len(fields) < minimum {
+		return nil, fmt.Errorf("expected at least %d values, got %d", minimum, len(fields))
+	}
+
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", f)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// faceIndexes returns the position and UV indexes of all faces.
+func (m *Mesh) faceIndexes() ([][3]int, [][3]int) {
+	xyzs := make([][3]int, len(m.Faces))
+	uvs := make([][3]int, len(m.Faces))
+	for i, f := range m.Faces {
+		xyzs[i] = f.Position
+		uvs[i] = f.UV
+	}
+	return xyzs, uvs
+}
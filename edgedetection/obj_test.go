@@ -0,0 +1,101 @@
+package edgedetection
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const objTriangle = "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\nvt 0 0\nvt 1 0\nvt 0 1\nvn 0 0 1\n"
+
+func TestReadObjFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    string
+		faces  []Face
+		groups []Group
+	}{
+		{
+			name:   "CRLF",
+			obj:    "v 0 0 0\r\nv 1 0 0\r\nv 0 1 0\r\nf 1 2 3\r\n",
+			faces:  []Face{{Position: [3]int{0, 1, 2}, UV: [3]int{-1, -1, -1}, Normal: [3]int{-1, -1, -1}}},
+			groups: []Group{{}},
+		},
+		{
+			name:   "line continuation",
+			obj:    "v 0 0 \\\n0\nv 1 0 0\nv 0 1 0\nf 1 \\\r\n2 3\n",
+			faces:  []Face{{Position: [3]int{0, 1, 2}, UV: [3]int{-1, -1, -1}, Normal: [3]int{-1, -1, -1}}},
+			groups: []Group{{}},
+		},
+		{
+			name:   "# inside names",
+			obj:    objTriangle + "o part#2 # the part\ng side\nusemtl mat#1 # red\nf 1 2 3\n",
+			faces:  []Face{{Position: [3]int{0, 1, 2}, UV: [3]int{-1, -1, -1}, Normal: [3]int{-1, -1, -1}}},
+			groups: []Group{{Object: "part#2", Name: "side", Material: "mat#1"}},
+		},
+		{
+			name:   "negative indexes",
+			obj:    objTriangle + "f -3/-3 -2/-2 -1/-1\n",
+			faces:  []Face{{Position: [3]int{1, 2, 3}, UV: [3]int{0, 1, 2}, Normal: [3]int{-1, -1, -1}}},
+			groups: []Group{{}},
+		},
+		{
+			name:   "position and normal",
+			obj:    objTriangle + "f 1//1 2//1 3//-1\n",
+			faces:  []Face{{Position: [3]int{0, 1, 2}, UV: [3]int{-1, -1, -1}, Normal: [3]int{0, 0, 0}}},
+			groups: []Group{{}},
+		},
+		{
+			name: "quad with UVs and normals",
+			obj:  objTriangle + "usemtl a\nf 1/1/1 2/2/1 4/3/1 3/1/1\nusemtl b\nf 1 2 3\n",
+			faces: []Face{
+				{Position: [3]int{0, 1, 3}, UV: [3]int{0, 1, 2}, Normal: [3]int{0, 0, 0}},
+				{Position: [3]int{0, 3, 2}, UV: [3]int{0, 2, 0}, Normal: [3]int{0, 0, 0}},
+				{Position: [3]int{0, 1, 2}, UV: [3]int{-1, -1, -1}, Normal: [3]int{-1, -1, -1}, Group: 1, Polygon: 1},
+			},
+			groups: []Group{{Material: "a"}, {Material: "b"}},
+		},
+	}
+
+	for _, test := range tests {
+		mesh, err := ReadObjFile(strings.NewReader(test.obj))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(mesh.Positions) < 3 || mesh.Positions[0] != [3]float64{} || mesh.Positions[2] != [3]float64{0, 1, 0} {
+			t.Errorf("%s: positions %v", test.name, mesh.Positions)
+		}
+		if !reflect.DeepEqual(mesh.Faces, test.faces) {
+			t.Errorf("%s: faces %+v, want %+v", test.name, mesh.Faces, test.faces)
+		}
+		if !reflect.DeepEqual(mesh.Groups, test.groups) {
+			t.Errorf("%s: groups %+v, want %+v", test.name, mesh.Groups, test.groups)
+		}
+	}
+}
+
+func TestReadObjFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  string
+		line int
+	}{
+		{"index 0", objTriangle + "f 0 1 2\n", 9},
+		{"position out of range", objTriangle + "f 1 2 5\n", 9},
+		{"negative index out of range", objTriangle + "f -5 1 2\n", 9},
+		{"UV out of range", objTriangle + "f 1/4 2/1 3/1\n", 9},
+		{"normal out of range", objTriangle + "f 1//2 2//1 3//1\n", 9},
+		{"line after continuation", "v 0 0 \\\n0\nv 1 0 0\nf 1 2\n", 4},
+		{"invalid number", "v 0 0 zero\n", 1},
+		{"too many slashes", objTriangle + "f 1/1/1/1 2 3\n", 9},
+	}
+
+	for _, test := range tests {
+		_, err := ReadObjFile(strings.NewReader(test.obj))
+		e, ok := err.(*ObjError)
+		if !ok || e.Line != test.line {
+			t.Errorf("%s: error %v, want an *ObjError on line %d", test.name, err, test.line)
+		}
+	}
+}
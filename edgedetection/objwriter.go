@@ -0,0 +1,134 @@
+package edgedetection
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// FaceLabels maps the labels of the samples to the faces of the mesh
+// they were extracted from. Faces without a sample are labelled -1, in
+// rawpoints mode a face gets the label of its last point.
+func (d *Data) FaceLabels(sampleLabels []int) []int {
+	labels := make([]int, len(d.indexXYZ))
+	for i := range labels {
+		labels[i] = -1
+	}
+
+	for i, face := range d.Faces {
+		if i < len(sampleLabels) {
+			labels[face] = sampleLabels[i]
+		}
+	}
+	return labels
+}
+
+// WriteLabelledObj writes the mesh with one group and one material
+// per label, so the segmentation can be opened in Blender or MeshLab.
+// faceLabels holds a label for every face, faces labelled -1 are
+// written to the group "unclustered". The materials are written to
+// mtl, mtlFile is the name the obj file refers to.
+func (d *Data) WriteLabelledObj(obj, mtl io.Writer, mtlFile string, faceLabels []int) error {
+	if len(faceLabels) != len(d.indexXYZ) {
+		return fmt.Errorf("got %d face labels for %d faces", len(faceLabels), len(d.indexXYZ))
+	}
+
+	// faces per label, the unclustered faces come first
+	maxLabel := -1
+	for _, l := range faceLabels {
+		if l > maxLabel {
+			maxLabel = l
+		}
+	}
+	groups := make([][]int, maxLabel+2)
+	for face, l := range faceLabels {
+		if l < -1 {
+			l = -1
+		}
+		groups[l+1] = append(groups[l+1], face)
+	}
+
+	err := d.writeMtl(mtl, maxLabel+1)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(obj)
+	fmt.Fprintf(writer, "mtllib %s\n", mtlFile)
+	for _, v := range d.coordXYZ {
+		fmt.Fprintf(writer, "v %f %f %f\n", v.X(), v.Y(), v.Z())
+	}
+	for _, vt := range d.coordUV {
+		fmt.Fprintf(writer, "vt %f %f\n", vt.X(), vt.Y())
+	}
+	var normals [][3]int
+	if d.mesh != nil && len(d.mesh.Normals) > 0 {
+		for _, vn := range d.mesh.Normals {
+			fmt.Fprintf(writer, "vn %f %f %f\n", vn.X(), vn.Y(), vn.Z())
+		}
+		normals = make([][3]int, len(d.mesh.Faces))
+		for i, f := range d.mesh.Faces {
+			normals[i] = f.Normal
+		}
+	}
+
+	for g, faces := range groups {
+		if len(faces) == 0 {
+			continue
+		}
+		name := materialName(g - 1)
+		fmt.Fprintf(writer, "g %s\nusemtl %s\n", name, name)
+		for _, face := range faces {
+			writer.WriteString("f")
+			for j := 0; j < 3; j++ {
+				writer.WriteString(" " + faceVertex(d, normals, face, j))
+			}
+			writer.WriteString("\n")
+		}
+	}
+
+	return writer.Flush()
+}
+
+// faceVertex formats vertex j of a face as "v", "v/vt", "v//vn" or "v/vt/vn".
+func faceVertex(d *Data, normals [][3]int, face, j int) string {
+	vertex := strconv.Itoa(d.indexXYZ[face][j] + 1)
+
+	uv := -1
+	if face < len(d.indexUV) {
+		uv = d.indexUV[face][j]
+	}
+	vn := -1
+	if face < len(normals) {
+		vn = normals[face][j]
+	}
+
+	switch {
+	case uv >= 0 && vn >= 0:
+		return vertex + "/" + strconv.Itoa(uv+1) + "/" + strconv.Itoa(vn+1)
+	case uv >= 0:
+		return vertex + "/" + strconv.Itoa(uv+1)
+	case vn >= 0:
+		return vertex + "//" + strconv.Itoa(vn+1)
+	}
+	return vertex
+}
+
+func (d *Data) writeMtl(mtl io.Writer, k int) error {
+	colors, _ := PaletteColors(k)
+
+	writer := bufio.NewWriter(mtl)
+	fmt.Fprintf(writer, "newmtl %s\nKa 0.000 0.000 0.000\nKd 0.502 0.502 0.502\nillum 1\n\n", materialName(-1))
+	for i, c := range colors {
+		fmt.Fprintf(writer, "newmtl %s\nKa 0.000 0.000 0.000\nKd %1.3f %1.3f %1.3f\nillum 1\n\n", materialName(i), c.R, c.G, c.B)
+	}
+	return writer.Flush()
+}
+
+func materialName(label int) string {
+	if label < 0 {
+		return "unclustered"
+	}
+	return fmt.Sprintf("cluster_%d", label)
+}
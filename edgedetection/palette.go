@@ -0,0 +1,280 @@
+package edgedetection
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// PaletteKind selects how the colors of a palette are generated.
+type PaletteKind int
+
+const (
+	// Categorical palettes maximize the perceptual distance
+	// between all colors (Glasbey et al., 2007).
+	Categorical PaletteKind = iota
+	// ColorblindSafe palettes start with the Okabe-Ito colors and
+	// maximize the distance for normal, protan and deutan vision.
+	ColorblindSafe
+)
+
+// Palette maps cluster ids to perceptually distinct colors.
+// The colors are generated greedily, so a palette of k colors is
+// always the prefix of a larger palette of the same kind and
+// every id keeps its color regardless of the number of clusters.
+type Palette struct {
+	kind   PaletteKind
+	colors []Color
+}
+
+// lightness bounds of generated colors, which keeps them
+// visible on white and black backgrounds
+const (
+	paletteMinLightness = 25.
+	paletteMaxLightness = 85.
+	// number of levels per RGB channel of the candidate colors
+	paletteLevels = 24
+)
+
+// NoiseColor is the neutral grey of negative ids, e.g. noise and outliers.
+// Generated colors keep their distance to it.
+var NoiseColor = Color{0.5, 0.5, 0.5}
+
+// okabeIto is the colorblind safe palette of Okabe and Ito (2008)
+// without black, which is reserved for the background, and without
+// yellow, which is lighter than paletteMaxLightness.
+var okabeIto = []Color{
+	{0.902, 0.624, 0.000}, // orange
+	{0.337, 0.706, 0.914}, // sky blue
+	{0.000, 0.620, 0.451}, // bluish green
+	{0.000, 0.447, 0.698}, // blue
+	{0.835, 0.369, 0.000}, // vermillion
+	{0.800, 0.475, 0.655}, // reddish purple
+}
+
+// NewPalette generates a palette with k colors.
+func NewPalette(kind PaletteKind, k int) *Palette {
+	p := &Palette{kind: kind}
+	p.extend(k)
+	return p
+}
+
+// Color returns the color of a cluster id.
+// The palette grows if the id is larger than the palette,
+// negative ids share NoiseColor.
+func (p *Palette) Color(id int) Color {
+	if id < 0 {
+		return NoiseColor
+	}
+	if id >= len(p.colors) {
+		p.extend(id + 1)
+	}
+	return p.colors[id]
+}
+
+// Colors returns all colors of the palette.
+func (p *Palette) Colors() []Color {
+	return p.colors
+}
+
+// Len returns the number of colors in the palette.
+func (p *Palette) Len() int {
+	return len(p.colors)
+}
+
+// Hex returns the color as "#rrggbb".
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", toByte(c.R), toByte(c.G), toByte(c.B))
+}
+
+// PaletteColors returns k categorical colors and their hex names.
+func PaletteColors(k int) ([]Color, []string) {
+	colors := NewPalette(Categorical, k).Colors()
+	names := make([]string, len(colors))
+	for i, c := range colors {
+		names[i] = c.Hex()
+	}
+	return colors, names
+}
+
+func toByte(v float32) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, float64(v))) * 255))
+}
+
+func (p *Palette) extend(k int) {
+	if k <= len(p.colors) {
+		return
+	}
+
+	candidates := paletteCandidates()
+	views := []func(Color) Color{identity}
+	if p.kind == ColorblindSafe {
+		views = append(views, protan, deutan)
+	}
+
+	// the background and the noise color are treated as used,
+	// so no color is close to white or to NoiseColor
+	used := []Color{{1, 1, 1}, NoiseColor}
+	used = append(used, p.colors...)
+	if p.kind == ColorblindSafe {
+		for _, c := range okabeIto {
+			if len(p.colors) >= k {
+				break
+			}
+			if !containsColor(p.colors, c) {
+				p.colors = append(p.colors, c)
+				used = append(used, c)
+			}
+		}
+	}
+
+	// smallest distance of every candidate to the used colors
+	minDist := make([]float64, len(candidates))
+	for i := range minDist {
+		minDist[i] = math.MaxFloat64
+	}
+	for _, u := range used {
+		updateMinDist(minDist, candidates, u, views)
+	}
+
+	for len(p.colors) < k {
+		best := 0
+		for i, d := range minDist {
+			if d > minDist[best] {
+				best = i
+			}
+		}
+		c := candidates[best].rgb
+		p.colors = append(p.colors, c)
+		updateMinDist(minDist, candidates, c, views)
+	}
+}
+
+type candidate struct {
+	rgb Color
+	// lab values for every view (normal, protan, deutan)
+	lab [3][3]float64
+}
+
+var (
+	candidatesOnce sync.Once
+	candidateList  []candidate
+)
+
+// paletteCandidates returns a grid of RGB colors within the lightness bounds.
+func paletteCandidates() []candidate {
+	candidatesOnce.Do(func() {
+		step := float32(1) / float32(paletteLevels-1)
+		for r := 0; r < paletteLevels; r++ {
+			for g := 0; g < paletteLevels; g++ {
+				for b := 0; b < paletteLevels; b++ {
+					c := Color{R: float32(r) * step, G: float32(g) * step, B: float32(b) * step}
+					lab := toLab(c)
+					if lab[0] < paletteMinLightness || lab[0] > paletteMaxLightness {
+						continue
+					}
+					candidateList = append(candidateList, candidate{
+						rgb: c,
+						lab: [3][3]float64{lab, toLab(protan(c)), toLab(deutan(c))},
+					})
+				}
+			}
+		}
+	})
+	return candidateList
+}
+
+func updateMinDist(minDist []float64, candidates []candidate, c Color, views []func(Color) Color) {
+	var labs [3][3]float64
+	for v, view := range views {
+		labs[v] = toLab(view(c))
+	}
+
+	for i, cand := range candidates {
+		for v := range views {
+			d := labDistance(cand.lab[v], labs[v])
+			if d < minDist[i] {
+				minDist[i] = d
+			}
+		}
+	}
+}
+
+func containsColor(colors []Color, c Color) bool {
+	for _, v := range colors {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+func labDistance(a, b [3]float64) float64 {
+	dl, da, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return math.Sqrt(dl*dl + da*da + db*db)
+}
+
+func identity(c Color) Color {
+	return c
+}
+
+// protan and deutan simulate dichromatic vision
+// after Viénot, Brettel and Mollon (1999).
+func protan(c Color) Color {
+	return simulate(c, [3][3]float64{
+		{0.11238, 0.88762, 0.00000},
+		{0.11238, 0.88762, 0.00000},
+		{0.00401, -0.00401, 1.00000},
+	})
+}
+
+func deutan(c Color) Color {
+	return simulate(c, [3][3]float64{
+		{0.29275, 0.70725, 0.00000},
+		{0.29275, 0.70725, 0.00000},
+		{-0.02234, 0.02234, 1.00000},
+	})
+}
+
+func simulate(c Color, m [3][3]float64) Color {
+	lin := [3]float64{toLinear(c.R), toLinear(c.G), toLinear(c.B)}
+	var out [3]float64
+	for i := range out {
+		out[i] = m[i][0]*lin[0] + m[i][1]*lin[1] + m[i][2]*lin[2]
+	}
+	return Color{R: fromLinear(out[0]), G: fromLinear(out[1]), B: fromLinear(out[2])}
+}
+
+func toLinear(v float32) float64 {
+	c := float64(v)
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func fromLinear(c float64) float32 {
+	c = math.Max(0, math.Min(1, c))
+	if c <= 0.0031308 {
+		return float32(c * 12.92)
+	}
+	return float32(1.055*math.Pow(c, 1/2.4) - 0.055)
+}
+
+// toLab converts an sRGB color to CIELAB (D65).
+func toLab(c Color) [3]float64 {
+	r, g, b := toLinear(c.R), toLinear(c.G), toLinear(c.B)
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func labF(t float64) float64 {
+	if t > 216./24389. {
+		return math.Cbrt(t)
+	}
+	return (24389./27.*t + 16) / 116
+}
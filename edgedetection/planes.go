@@ -0,0 +1,124 @@
+package edgedetection
+
+import (
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/edgeDetection/geometry"
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// PlaneSegment is a plane that was removed from the selected points
+// before the clustering. It holds the same per point data as Data,
+// Normale and Barycenter in barycenter mode, Points, PointNormals
+// and Curvature in rawpoints mode, Faces, Pixels and Regions in both modes.
+type PlaneSegment struct {
+	Plane geometry.Plane
+
+	Faces   []int
+	Pixels  []image.Point
+	Regions []int
+
+	Normale    [][]float64
+	Barycenter [][]float64
+
+	Points       [][]float64
+	PointNormals [][]float64
+	Curvature    []float64
+}
+
+// segmentPlanes removes up to config.Planes dominant planes from the
+// selected points with sequential RANSAC. Only the remaining points
+// stay in Data and are clustered, it is an error if no point remains.
+func (d *Data) segmentPlanes() error {
+	ransac := geometry.DefaultRansac()
+	ransac.Iterations = d.config.PlaneIterations
+	ransac.Tolerance = d.config.PlaneTolerance
+	ransac.MaxNormalAngle = d.config.PlaneMaxNormalAngle
+
+	var points, normals []mgl64.Vec3
+	if d.config.Mode == "barycenter" {
+		points = toVec3(d.Barycenter)
+		normals = toVec3(d.Normale)
+	} else {
+		points = toVec3(d.Points)
+		normals = toVec3(d.PointNormals)
+	}
+
+	segments, remaining := ransac.Segment(points, normals, d.config.Planes, d.config.PlaneMinInliers, d.rng())
+	if len(segments) > 0 && len(remaining) == 0 {
+		return fmt.Errorf("no points left after plane removal: %d planes cover all %d points", len(segments), len(points))
+	}
+	for _, s := range segments {
+		plane := PlaneSegment{
+			Plane:   s.Plane,
+			Faces:   selectInts(d.Faces, s.Inliers),
+			Pixels:  selectPixels(d.Pixels, s.Inliers),
+			Regions: selectInts(d.Regions, s.Inliers),
+		}
+		if d.config.Mode == "barycenter" {
+			plane.Normale = selectRows(d.Normale, s.Inliers)
+			plane.Barycenter = selectRows(d.Barycenter, s.Inliers)
+		} else {
+			plane.Points = selectRows(d.Points, s.Inliers)
+			plane.PointNormals = selectRows(d.PointNormals, s.Inliers)
+			plane.Curvature = selectFloats(d.Curvature, s.Inliers)
+		}
+		d.Planes = append(d.Planes, plane)
+		log.Println("Plane with", len(s.Inliers), "points:", s.Plane.Normal, s.Plane.D)
+	}
+
+	d.Faces = selectInts(d.Faces, remaining)
+	d.Pixels = selectPixels(d.Pixels, remaining)
+	d.Regions = selectInts(d.Regions, remaining)
+	if d.config.Mode == "barycenter" {
+		d.Normale = selectRows(d.Normale, remaining)
+		d.Barycenter = selectRows(d.Barycenter, remaining)
+	} else {
+		d.Points = selectRows(d.Points, remaining)
+		d.PointNormals = selectRows(d.PointNormals, remaining)
+		d.Curvature = selectFloats(d.Curvature, remaining)
+	}
+	return nil
+}
+
+func toVec3(rows [][]float64) []mgl64.Vec3 {
+	vectors := make([]mgl64.Vec3, len(rows))
+	for i, r := range rows {
+		vectors[i] = mgl64.Vec3{r[0], r[1], r[2]}
+	}
+	return vectors
+}
+
+func selectRows(rows [][]float64, indexes []int) [][]float64 {
+	selected := make([][]float64, len(indexes))
+	for i, index := range indexes {
+		selected[i] = rows[index]
+	}
+	return selected
+}
+
+func selectInts(values []int, indexes []int) []int {
+	selected := make([]int, len(indexes))
+	for i, index := range indexes {
+		selected[i] = values[index]
+	}
+	return selected
+}
+
+func selectFloats(values []float64, indexes []int) []float64 {
+	selected := make([]float64, len(indexes))
+	for i, index := range indexes {
+		selected[i] = values[index]
+	}
+	return selected
+}
+
+func selectPixels(pixels []image.Point, indexes []int) []image.Point {
+	selected := make([]image.Point, len(indexes))
+	for i, index := range indexes {
+		selected[i] = pixels[index]
+	}
+	return selected
+}
@@ -0,0 +1,317 @@
+package edgedetection
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// PlyLoader reads ASCII and binary (little and big endian) PLY files.
+// Texture coordinates are read from the vertex properties
+// "s"/"t", "u"/"v" or "texture_u"/"texture_v", or per face corner
+// from a "texcoord" list. Elements other than vertex and face are skipped.
+type PlyLoader struct{}
+
+type plyProperty struct {
+	name string
+	// kind is the value type, count the type of the
+	// length of a list property or empty for scalars
+	kind  string
+	count string
+}
+
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// plyValues reads the values of one element either from
+// text fields or from binary data.
+type plyValues interface {
+	next(kind string) (float64, error)
+	endElement() error
+}
+
+// Load ...
+func (PlyLoader) Load(r io.Reader) (*Mesh, error) {
+	reader := bufio.NewReader(r)
+	format, elements, err := readPlyHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var values plyValues
+	switch format {
+	case "ascii":
+		values = &plyText{reader: reader}
+	case "binary_little_endian":
+		values = &plyBinary{reader: reader, order: binary.LittleEndian}
+	case "binary_big_endian":
+		values = &plyBinary{reader: reader, order: binary.BigEndian}
+	default:
+		return nil, fmt.Errorf("ply: unknown format %q", format)
+	}
+
+	mesh := &Mesh{Groups: []Group{{}}}
+	for _, e := range elements {
+		for i := 0; i < e.count; i++ {
+			err = mesh.readPlyElement(e, values)
+			if err != nil {
+				return nil, fmt.Errorf("ply: %s %d: %v", e.name, i, err)
+			}
+		}
+	}
+
+	return mesh, mesh.checkIndexes()
+}
+
+func readPlyHeader(reader *bufio.Reader) (string, []plyElement, error) {
+	var format string
+	var elements []plyElement
+	for line := 0; ; line++ {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("ply: incomplete header: %v", err)
+		}
+
+		fields := strings.Fields(text)
+		if line == 0 {
+			if len(fields) != 1 || fields[0] != "ply" {
+				return "", nil, fmt.Errorf("ply: missing magic number")
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return "", nil, fmt.Errorf("ply header line %d: missing format", line+1)
+			}
+			format = fields[1]
+		case "element":
+			if len(fields) != 3 {
+				return "", nil, fmt.Errorf("ply header line %d: invalid element", line+1)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return "", nil, fmt.Errorf("ply header line %d: invalid element count %q", line+1, fields[2])
+			}
+			elements = append(elements, plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, fmt.Errorf("ply header line %d: property without element", line+1)
+			}
+			var p plyProperty
+			switch {
+			case len(fields) == 5 && fields[1] == "list":
+				p = plyProperty{name: fields[4], kind: fields[3], count: fields[2]}
+			case len(fields) == 3:
+				p = plyProperty{name: fields[2], kind: fields[1]}
+			default:
+				return "", nil, fmt.Errorf("ply header line %d: invalid property", line+1)
+			}
+			if plySize(p.kind) == 0 || (p.count != "" && plySize(p.count) == 0) {
+				return "", nil, fmt.Errorf("ply header line %d: unknown type", line+1)
+			}
+			e := &elements[len(elements)-1]
+			e.properties = append(e.properties, p)
+		case "end_header":
+			return format, elements, nil
+		}
+	}
+}
+
+func (m *Mesh) readPlyElement(e plyElement, values plyValues) error {
+	var position, normal mgl64.Vec3
+	var uv mgl64.Vec2
+	hasNormal, hasUV := false, false
+	var indexes []int
+	var texcoords []float64
+
+	for _, p := range e.properties {
+		if p.count != "" {
+			n, err := values.next(p.count)
+			if err != nil {
+				return err
+			}
+			if n < 0 {
+				return fmt.Errorf("negative list length")
+			}
+			list := make([]float64, int(n))
+			for i := range list {
+				list[i], err = values.next(p.kind)
+				if err != nil {
+					return err
+				}
+			}
+			switch p.name {
+			case "vertex_indices", "vertex_index":
+				indexes = make([]int, len(list))
+				for i, v := range list {
+					indexes[i] = int(v)
+				}
+			case "texcoord":
+				texcoords = list
+			}
+			continue
+		}
+
+		v, err := values.next(p.kind)
+		if err != nil {
+			return err
+		}
+		switch p.name {
+		case "x", "y", "z":
+			position[p.name[0]-'x'] = v
+		case "nx", "ny", "nz":
+			normal[p.name[1]-'x'] = v
+			hasNormal = true
+		case "s", "u", "texture_u":
+			uv[0] = v
+			hasUV = true
+		case "t", "v", "texture_v":
+			uv[1] = v
+			hasUV = true
+		}
+	}
+
+	switch e.name {
+	case "vertex":
+		m.Positions = append(m.Positions, position)
+		if hasNormal {
+			m.Normals = append(m.Normals, normal)
+		}
+		if hasUV {
+			m.UVs = append(m.UVs, uv)
+		}
+	case "face":
+		if len(indexes) < 3 {
+			return fmt.Errorf("face needs at least 3 vertices, got %d", len(indexes))
+		}
+		m.addPlyFace(indexes, texcoords)
+	}
+
+	return values.endElement()
+}
+
+// addPlyFace adds a face, using the vertex attributes as UVs and normals
+// or the per corner texcoord list if there is one.
+func (m *Mesh) addPlyFace(indexes []int, texcoords []float64) {
+	var uvs, normals []int
+	if len(m.Normals) == len(m.Positions) {
+		normals = indexes
+	}
+
+	switch {
+	case len(texcoords) == 2*len(indexes):
+		uvs = make([]int, len(indexes))
+		for i := range uvs {
+			m.UVs = append(m.UVs, mgl64.Vec2{texcoords[2*i], texcoords[2*i+1]})
+			uvs[i] = len(m.UVs) - 1
+		}
+	case len(m.UVs) == len(m.Positions):
+		uvs = indexes
+	}
+
+	m.addPolygon(indexes, uvs, normals, 0)
+}
+
+// checkIndexes validates the position indexes of all faces.
+func (m *Mesh) checkIndexes() error {
+	for _, f := range m.Faces {
+		for _, p := range f.Position {
+			if p < 0 || p >= len(m.Positions) {
+				return fmt.Errorf("face %d: vertex index %d is out of range", f.Polygon, p)
+			}
+		}
+	}
+	return nil
+}
+
+func plySize(kind string) int {
+	switch kind {
+	case "char", "int8", "uchar", "uint8":
+		return 1
+	case "short", "int16", "ushort", "uint16":
+		return 2
+	case "int", "int32", "uint", "uint32", "float", "float32":
+		return 4
+	case "double", "float64":
+		return 8
+	}
+	return 0
+}
+
+type plyText struct {
+	reader *bufio.Reader
+	fields []string
+}
+
+func (p *plyText) next(kind string) (float64, error) {
+	for len(p.fields) == 0 {
+		line, err := p.reader.ReadString('\n')
+		if line == "" && err != nil {
+			return 0, fmt.Errorf("unexpected end of file")
+		}
+		p.fields = strings.Fields(line)
+	}
+
+	v, err := strconv.ParseFloat(p.fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.fields[0])
+	}
+	p.fields = p.fields[1:]
+	return v, nil
+}
+
+// endElement drops the rest of the line, every element is one line.
+func (p *plyText) endElement() error {
+	p.fields = nil
+	return nil
+}
+
+type plyBinary struct {
+	reader io.Reader
+	order  binary.ByteOrder
+	buffer [8]byte
+}
+
+func (p *plyBinary) next(kind string) (float64, error) {
+	b := p.buffer[:plySize(kind)]
+	_, err := io.ReadFull(p.reader, b)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected end of file")
+	}
+
+	switch kind {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(p.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(p.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(p.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(p.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(p.order.Uint32(b))), nil
+	}
+	return math.Float64frombits(p.order.Uint64(b)), nil
+}
+
+func (p *plyBinary) endElement() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package edgedetection
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// fillRegions rasterises the contour polygons into the label mask,
+// so the region of a pixel is a single lookup. regionOf holds the
+// region of every contour, contours with -1 are not filled.
+func (i *ImageCV) fillRegions(contours []Contour, regionOf []int) {
+	rows, cols := i.mat.Rows(), i.mat.Cols()
+	i.regions = make([]int32, rows*cols)
+	for p := range i.regions {
+		i.regions[p] = -1
+	}
+
+	for k, c := range contours {
+		if regionOf[k] < 0 || len(c.Points) == 0 {
+			continue
+		}
+		region := int32(regionOf[k])
+		set := func(x, y int) {
+			if x >= 0 && x < cols && y >= 0 && y < rows {
+				i.regions[y*cols+x] = region
+			}
+		}
+
+		// interior, pixel centers inside the polygon with the even odd rule
+		bounds := c.Bounds.Intersect(image.Rect(0, 0, cols, rows))
+		var crossings []float64
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			cy := float64(y)
+			crossings = crossings[:0]
+			for e, a := range c.Points {
+				b := c.Points[(e+1)%len(c.Points)]
+				if (a.Y <= y) == (b.Y <= y) {
+					continue
+				}
+				t := (cy - float64(a.Y)) / float64(b.Y-a.Y)
+				crossings = append(crossings, float64(a.X)+t*float64(b.X-a.X))
+			}
+			sort.Float64s(crossings)
+			for s := 0; s+1 < len(crossings); s += 2 {
+				from := int(math.Ceil(crossings[s]))
+				to := int(math.Floor(crossings[s+1]))
+				for x := from; x <= to; x++ {
+					set(x, y)
+				}
+			}
+		}
+
+		// border, the contour points are pixels of the region
+		for e, a := range c.Points {
+			b := c.Points[(e+1)%len(c.Points)]
+			linePixels(a, b, set)
+		}
+	}
+}
+
+// region returns the region of a pixel, -1 outside of all regions.
+func (i *ImageCV) region(row, col int) int {
+	return int(i.regions[row*i.mat.Cols()+col])
+}
+
+// linePixels calls set for every pixel of the line from a to b
+// with Bresenham's algorithm.
+func linePixels(a, b image.Point, set func(x, y int)) {
+	dx := b.X - a.X
+	if dx < 0 {
+		dx = -dx
+	}
+	dy := -(b.Y - a.Y)
+	if dy > 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		set(a.X, a.Y)
+		if a == b {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			a.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			a.Y += sy
+		}
+	}
+}
@@ -2,102 +2,327 @@ package edgedetection
 
 import (
 	"fmt"
-	"sort"
+	"image"
+	"log"
+	"runtime"
+	"sync"
 
-	"gocv.io/x/gocv"
+	"github.com/edgeDetection/geometry"
+	"github.com/go-gl/mathgl/mgl64"
 )
 
-func (d *Data) correspondendingPoints(modus string) *ImageCV {
+func (d *Data) correspondendingPoints() (*ImageCV, error) {
 
-	minHeight := d.getminheight()
-	fmt.Println("min height: ", minHeight)
-	upperZThreshold := 0.5 //20cm
-	lowerZThreshold := 0.02
+	err := d.fitGroundPlane()
+	if err != nil {
+		return nil, err
+	}
+	if d.config.Debug != nil {
+		log.Println("Ground plane:", d.ground.Normal, d.ground.D)
+	}
+	lower := d.config.MinGroundDistance
+	upper := d.config.MaxGroundDistance
 
-	var whitePoints gocv.Mat
-	switch string(modus) {
+	var whitePoints BackendImage
+	switch d.config.Mode {
 	case "barycenter":
-		whitePoints = d.findNormalsAndBarycenter(upperZThreshold, lowerZThreshold, minHeight)
+		whitePoints = d.findNormalsAndBarycenter(lower, upper)
 	case "rawpoints":
-		whitePoints = d.findRawPoints(upperZThreshold, lowerZThreshold, minHeight)
+		whitePoints = d.findRawPoints(lower, upper)
+		d.estimatePointNormals()
 	}
 
-	return &ImageCV{mat: whitePoints}
+	return d.img.with(whitePoints), nil
 }
 
-func (d *Data) findNormalsAndBarycenter(upperZThreshold, lowerZThreshold, minHeight float64) gocv.Mat {
+// groundCandidates is the number of planes close to the up direction
+// that are compared to find the ground.
+const groundCandidates = 4
+
+// fitGroundPlane finds the ground among the largest planes of the mesh
+// vertices whose normal is close to the configured up direction.
+// Planes with most vertices below them (ceilings, table tops seen from
+// above the floor) and planes with less than a quarter of the inliers
+// of the largest plane are skipped, the lowest remaining plane along
+// the up direction is the ground.
+func (d *Data) fitGroundPlane() error {
+	ransac := geometry.DefaultRansac()
+	ransac.Iterations = d.config.GroundIterations
+	ransac.Tolerance = d.config.GroundTolerance
+	ransac.Up = mgl64.Vec3(d.config.Up)
+	ransac.MaxTilt = d.config.GroundMaxTilt
+
+	segments, _ := ransac.Segment(d.coordXYZ, nil, groundCandidates, 3, d.rng())
+	if len(segments) == 0 {
+		return fmt.Errorf("ground plane: %v", geometry.ErrNoPlane)
+	}
 
-	whitePoints := gocv.NewMatWithSize(d.img.mat.Rows(), d.img.mat.Cols(), gocv.MatTypeCV8U)
-	wp := 0
-	for i := 0; i < len(d.indexUV); i += 2 {
+	up := ransac.Up.Normalize()
+	found := false
+	var lowest float64
+	for _, segment := range segments {
+		if 4*len(segment.Inliers) < len(segments[0].Inliers) {
+			continue
+		}
 
-		pixel1, row1, col1 := d.checkCurrentPixel(d.indexUV[i][0])
-		pixel2, row2, col2 := d.checkCurrentPixel(d.indexUV[i][1])
-		pixel3, row3, col3 := d.checkCurrentPixel(d.indexUV[i][2])
+		below := 0
+		for _, v := range d.coordXYZ {
+			if segment.Plane.Distance(v) < -ransac.Tolerance {
+				below++
+			}
+		}
+		if 2*below > len(d.coordXYZ) {
+			continue
+		}
 
-		if pixel1 || pixel2 || pixel3 {
-			check := d.calcBarycenterFacenormal(d.indexXYZ[i], upperZThreshold, lowerZThreshold, minHeight)
-			if check {
+		height := 0.
+		for _, i := range segment.Inliers {
+			height += d.coordXYZ[i].Dot(up)
+		}
+		height /= float64(len(segment.Inliers))
+		if !found || height < lowest {
+			d.ground, lowest, found = segment.Plane, height, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("ground plane: every plane has most vertices below it")
+	}
+	return nil
+}
 
-				whitePoints.SetUCharAt(row1, col1, uint8(255))
-				whitePoints.SetUCharAt(row2, col2, uint8(255))
-				whitePoints.SetUCharAt(row3, col3, uint8(255))
-				wp++
-			}
+// estimatePointNormals computes PCA normals and curvature of the
+// raw points from their nearest mesh vertices.
+func (d *Data) estimatePointNormals() {
+	var viewpoint mgl64.Vec3
+	if d.config.Viewpoint != nil {
+		viewpoint = mgl64.Vec3(*d.config.Viewpoint)
+	} else {
+		for _, v := range d.coordXYZ {
+			viewpoint = viewpoint.Add(v)
 		}
+		viewpoint = viewpoint.Mul(1 / float64(len(d.coordXYZ)))
 	}
-	fmt.Println("Number of white points: ", wp)
-	return whitePoints
+
+	tree := geometry.NewKDTree(d.coordXYZ)
+	normals, curvature := geometry.PointNormals(tree, d.coordXYZ, toVec3(d.Points), d.config.NormalNeighbours, viewpoint)
+	d.PointNormals = make([][]float64, len(normals))
+	for i, n := range normals {
+		d.PointNormals[i] = []float64{n.X(), n.Y(), n.Z()}
+	}
+	d.Curvature = curvature
+}
+
+// Normals returns the normals of the points to cluster, the face
+// normals in barycenter mode and the point normals in rawpoints mode.
+func (d *Data) Normals() [][]float64 {
+	if d.config != nil && d.config.Mode == "rawpoints" {
+		return d.PointNormals
+	}
+	return d.Normale
 }
 
-func (d *Data) checkCurrentPixel(iuv int) (bool, int, int) {
+// Samples returns the positions of the points to cluster, the
+// barycenters in barycenter mode and the points in rawpoints mode.
+func (d *Data) Samples() [][]float64 {
+	if d.config != nil && d.config.Mode == "rawpoints" {
+		return d.Points
+	}
+	return d.Barycenter
+}
 
-	u := d.coordUV[iuv].X()
-	v := d.coordUV[iuv].Y()
+// FaceArea returns the area of a face of the mesh.
+func (d *Data) FaceArea(face int) float64 {
+	index := d.indexXYZ[face]
+	a := d.coordXYZ[index[0]]
+	b := d.coordXYZ[index[1]]
+	c := d.coordXYZ[index[2]]
+	return b.Sub(a).Cross(c.Sub(a)).Len() / 2
+}
+
+// GroundPlane returns the ground plane, its normal points upwards.
+func (d *Data) GroundPlane() geometry.Plane {
+	return d.ground
+}
+
+// faceSample is a point extracted from one face of the mesh.
+type faceSample struct {
+	face int
+	// vertex is the mesh vertex of a raw point, -1 for a barycenter
+	vertex int
+	point  mgl64.Vec3
+	normal mgl64.Vec3
+	pixel  image.Point
+	// region is the region of interest of the first edge pixel
+	region int
+	// white are the pixels marked in the white points image
+	white []image.Point
+}
+
+// collectSamples calls visit for every face of the mesh, spread over
+// all cores. The samples are returned in face order.
+func (d *Data) collectSamples(visit func(face int, samples []faceSample) []faceSample) []faceSample {
+	faces := len(d.indexUV)
+	workers := runtime.NumCPU()
+	chunk := (faces + workers - 1) / workers
+	var results [][]faceSample
+	var wg sync.WaitGroup
+	for start := 0; start < faces; start += chunk {
+		end := minInt(start+chunk, faces)
+		results = append(results, nil)
+		wg.Add(1)
+		go func(result *[]faceSample, start, end int) {
+			defer wg.Done()
+			var samples []faceSample
+			for face := start; face < end; face++ {
+				samples = visit(face, samples)
+			}
+			*result = samples
+		}(&results[len(results)-1], start, end)
+	}
+	wg.Wait()
 
-	row := int((1-v)*float64(d.img.height) - 1)
-	col := int(u * float64(d.img.width))
+	var samples []faceSample
+	for _, r := range results {
+		samples = append(samples, r...)
+	}
+	return samples
+}
 
-	pixValue := d.img.mat.GetUCharAt(row, col)
-	if pixValue != uint8(0) {
-		for _, r := range d.img.rects {
-			if (row >= r.Min.Y) && (row <= r.Max.Y) && (col >= r.Min.X) && (col <= r.Max.X) {
-				return true, row, col
+func (d *Data) findNormalsAndBarycenter(lower, upper float64) BackendImage {
+
+	samples := d.collectSamples(func(face int, samples []faceSample) []faceSample {
+		var white []image.Point
+		region := -1
+		for _, iuv := range d.indexUV[face] {
+			if pixel, row, col, r := d.checkCurrentPixel(iuv); pixel {
+				white = append(white, image.Pt(col, row))
+				if region < 0 {
+					region = r
+				}
 			}
 		}
-	} else {
-		return false, 0, 0
+		if len(white) == 0 {
+			return samples
+		}
+
+		sample, check := d.calcBarycenterFacenormal(face, lower, upper)
+		if !check {
+			return samples
+		}
+		sample.white = white
+		sample.region = region
+		return append(samples, sample)
+	})
+
+	whitePoints := d.img.backend.New(d.img.mat.Rows(), d.img.mat.Cols())
+	d.Normale = make([][]float64, len(samples))
+	d.Barycenter = make([][]float64, len(samples))
+	d.Faces = make([]int, len(samples))
+	d.Pixels = make([]image.Point, len(samples))
+	d.Regions = make([]int, len(samples))
+	for i, s := range samples {
+		d.Normale[i] = []float64{s.normal.X(), s.normal.Y(), s.normal.Z()}
+		d.Barycenter[i] = []float64{s.point.X(), s.point.Y(), s.point.Z()}
+		d.Faces[i] = s.face
+		d.Pixels[i] = s.pixel
+		d.Regions[i] = s.region
+		for _, p := range s.white {
+			whitePoints.Set(p.Y, p.X, uint8(255))
+		}
+	}
+	fmt.Println("Number of white points: ", len(samples))
+	return whitePoints
+}
+
+// checkCurrentPixel returns if the texture pixel of iuv is an edge
+// pixel inside a region of interest, its row and column and the region.
+// A pixel is an edge if its dilated edge confidence reaches the edge threshold.
+func (d *Data) checkCurrentPixel(iuv int) (bool, int, int, int) {
+
+	row, col, ok := d.uvPixel(iuv)
+	if !ok {
+		return false, 0, 0, -1
+	}
+
+	pixValue := d.img.mat.At(row, col)
+	if pixValue < d.img.edgeLevel || pixValue == uint8(0) {
+		return false, 0, 0, -1
+	}
+	region := d.img.region(row, col)
+	if region < 0 {
+		return false, 0, 0, -1
 	}
-	return false, 0, 0
+	return true, row, col, region
 }
 
-func (d *Data) findRawPoints(upperZThreshold, lowerZThreshold, minHeight float64) gocv.Mat {
+// uvPixel returns the texture pixel of the texture coordinate iuv,
+// false for a face vertex without texture coordinates or outside of
+// the texture.
+func (d *Data) uvPixel(iuv int) (int, int, bool) {
+	if iuv < 0 {
+		return 0, 0, false
+	}
+	return d.texturePixel(d.coordUV[iuv])
+}
 
-	whitePoints := gocv.NewMatWithSize(d.img.mat.Rows(), d.img.mat.Cols(), gocv.MatTypeCV8U)
-	wp := 0
+func (d *Data) texturePixel(uv mgl64.Vec2) (int, int, bool) {
+	row := int((1-uv.Y())*float64(d.img.height) - 1)
+	col := int(uv.X() * float64(d.img.width))
+	if row < 0 || row >= d.img.height || col < 0 || col >= d.img.width {
+		return 0, 0, false
+	}
+	return row, col, true
+}
 
-	for i := 0; i < len(d.indexUV); i += 3 {
-		//
+func (d *Data) findRawPoints(lower, upper float64) BackendImage {
 
-		iXYZ := d.indexXYZ[i]
+	samples := d.collectSamples(func(face int, samples []faceSample) []faceSample {
+		iXYZ := d.indexXYZ[face]
 		for ii := 0; ii < len(iXYZ); ii++ {
 
-			pixel, row, col := d.checkCurrentPixel(d.indexUV[i][ii])
+			pixel, row, col, region := d.checkCurrentPixel(d.indexUV[face][ii])
 			vertex := d.coordXYZ[iXYZ[ii]]
 
-			if pixel && (vertex.Z() >= minHeight+lowerZThreshold) && (vertex.Z() <= minHeight+upperZThreshold) {
-				wp++
-				whitePoints.SetUCharAt(row, col, uint8(255))
-				d.Points = append(d.Points, []float64{vertex.X(), vertex.Y(), vertex.Z()})
+			distance := d.ground.Distance(vertex)
+			if pixel && (distance >= lower) && (distance <= upper) {
+				samples = append(samples, faceSample{
+					face:   face,
+					vertex: iXYZ[ii],
+					point:  vertex,
+					pixel:  image.Pt(col, row),
+					region: region,
+				})
 			}
 		}
+		return samples
+	})
+
+	// a vertex is shared by several faces, the first face is kept
+	whitePoints := d.img.backend.New(d.img.mat.Rows(), d.img.mat.Cols())
+	seen := make([]bool, len(d.coordXYZ))
+	for _, s := range samples {
+		whitePoints.Set(s.pixel.Y, s.pixel.X, uint8(255))
+		if seen[s.vertex] {
+			continue
+		}
+		seen[s.vertex] = true
+		d.Points = append(d.Points, []float64{s.point.X(), s.point.Y(), s.point.Z()})
+		d.Faces = append(d.Faces, s.face)
+		d.Pixels = append(d.Pixels, s.pixel)
+		d.Regions = append(d.Regions, s.region)
 	}
 
-	fmt.Println("Number of white points: ", wp)
+	fmt.Println("Number of white points: ", len(d.Points))
 	return whitePoints
 }
 
-func (d *Data) calcBarycenterFacenormal(indexXYZ [3]int, upperZThreshold, lowerZThreshold, minHeight float64) bool {
+// calcBarycenterFacenormal returns the barycenter and normal of the face,
+// false if the barycenter is outside of the ground distance range or
+// the face has no texture pixel.
+// The pixel of the sample is the texture pixel of the barycenter.
+func (d *Data) calcBarycenterFacenormal(face int, lower, upper float64) (faceSample, bool) {
+
+	indexXYZ := d.indexXYZ[face]
 
 	vertex1 := d.coordXYZ[indexXYZ[0]]
 	vertex2 := d.coordXYZ[indexXYZ[1]]
@@ -107,34 +332,36 @@ func (d *Data) calcBarycenterFacenormal(indexXYZ [3]int, upperZThreshold, lowerZ
 	vec31 := vertex3.Sub(vertex1)
 
 	barycenter := vertex1.Add(vertex2).Add(vertex3).Mul(1. / 3.)
-	if (barycenter.Z() <= minHeight+lowerZThreshold) || (barycenter.Z() >= minHeight+upperZThreshold) {
-		return false
+	distance := d.ground.Distance(barycenter)
+	if (distance < lower) || (distance > upper) {
+		return faceSample{}, false
 	}
 
 	cross := vec21.Cross(vec31)
 	norm := cross.Normalize()
 
-	d.Normale = append(d.Normale, []float64{norm.X(), norm.Y(), norm.Z()})
-	d.Barycenter = append(d.Barycenter, []float64{barycenter.X(), barycenter.Y(), barycenter.Z()})
-
-	return true
-}
-
-func (d *Data) getminheight() float64 {
-
-	height := make([]float64, len(d.coordXYZ))
-	for i := 0; i < len(height); i++ {
-		height[i] = d.coordXYZ[i].Z()
+	// barycenter of the texture coordinates the face has
+	var uv mgl64.Vec2
+	n := 0
+	for _, iuv := range d.indexUV[face] {
+		if iuv >= 0 {
+			uv = uv.Add(d.coordUV[iuv])
+			n++
+		}
 	}
-	return average(height, 1000)
-}
-
-func average(height []float64, count int) float64 {
-	sort.Float64s(height)
-	sum := 0.0
-	for i := 0; i < count; i++ {
-		sum += height[i]
+	if n == 0 {
+		return faceSample{}, false
+	}
+	row, col, ok := d.texturePixel(uv.Mul(1 / float64(n)))
+	if !ok {
+		return faceSample{}, false
 	}
-	sum = sum / float64(count)
-	return sum
+
+	return faceSample{
+		face:   face,
+		vertex: -1,
+		point:  barycenter,
+		normal: norm,
+		pixel:  image.Pt(col, row),
+	}, true
 }
@@ -1,6 +1,10 @@
 package edgedetection
 
-import "strings"
+import (
+	"math/rand"
+	"strings"
+	"time"
+)
 
 var Colorsrand = []string{
 	"aliceblue",
@@ -165,6 +169,12 @@ func IsColorName(name string) (Color, bool) {
 	return c, ok
 }
 
+// NewRand returns a random generator seeded with the current time.
+// It is used wherever no seed has been set.
+func NewRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
 // mapColorNames maps standard web color names to a Color with
 // the standard web color's RGB component values
 var mapColorNames = map[string]Color{
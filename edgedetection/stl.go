@@ -0,0 +1,132 @@
+package edgedetection
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// StlLoader reads ASCII and binary STL files.
+// STL has no texture coordinates, the face normals are
+// stored as one normal per face.
+type StlLoader struct{}
+
+// Load ...
+func (StlLoader) Load(r io.Reader) (*Mesh, error) {
+	reader := bufio.NewReader(r)
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	if isAsciiStl(head) {
+		return readAsciiStl(reader)
+	}
+	return readBinaryStl(reader)
+}
+
+// isAsciiStl reports if the bytes start with a "solid" line followed by
+// a "facet" or "endsolid" line. The 80 byte header of a binary STL may
+// start with "solid" and mention facets too, but the next line is binary.
+func isAsciiStl(head []byte) bool {
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	if !bytes.HasPrefix(trimmed, []byte("solid")) {
+		return false
+	}
+	i := bytes.IndexByte(trimmed, '\n')
+	if i < 0 {
+		return false
+	}
+	next := bytes.TrimLeft(trimmed[i+1:], " \t\r\n")
+	return bytes.HasPrefix(next, []byte("facet")) || bytes.HasPrefix(next, []byte("endsolid"))
+}
+
+func readBinaryStl(r io.Reader) (*Mesh, error) {
+	header := make([]byte, 84)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, fmt.Errorf("stl: cannot read header: %v", err)
+	}
+	count := binary.LittleEndian.Uint32(header[80:])
+
+	b := newMeshBuilder()
+	record := make([]byte, 50)
+	values := make([]float64, 12)
+	for t := uint32(0); t < count; t++ {
+		_, err = io.ReadFull(r, record)
+		if err != nil {
+			return nil, fmt.Errorf("stl: triangle %d: %v", t, err)
+		}
+		for i := range values {
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(record[4*i:])))
+		}
+		b.addTriangle(values)
+	}
+
+	return b.mesh, nil
+}
+
+func readAsciiStl(r io.Reader) (*Mesh, error) {
+	scanner := bufio.NewScanner(r)
+	b := newMeshBuilder()
+
+	values := make([]float64, 0, 12)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var numbers []string
+		switch {
+		case fields[0] == "facet" && len(fields) == 5 && fields[1] == "normal":
+			values = values[:0]
+			numbers = fields[2:]
+		case fields[0] == "vertex" && len(fields) == 4:
+			numbers = fields[1:]
+		case fields[0] == "endfacet":
+			if len(values) != 12 {
+				return nil, fmt.Errorf("stl line %d: facet needs a normal and 3 vertices", line)
+			}
+			b.addTriangle(values)
+			continue
+		default:
+			continue
+		}
+
+		for _, n := range numbers {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return nil, fmt.Errorf("stl line %d: invalid number %q", line, n)
+			}
+			values = append(values, v)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return b.mesh, nil
+}
+
+// addTriangle adds a triangle from a normal followed by three vertices.
+func (b *meshBuilder) addTriangle(values []float64) {
+	b.mesh.Normals = append(b.mesh.Normals, mgl64.Vec3{values[0], values[1], values[2]})
+	normal := len(b.mesh.Normals) - 1
+
+	positions := []int{
+		b.position(values[3], values[4], values[5]),
+		b.position(values[6], values[7], values[8]),
+		b.position(values[9], values[10], values[11]),
+	}
+	b.mesh.addPolygon(positions, nil, []int{normal, normal, normal}, 0)
+}
@@ -0,0 +1,141 @@
+package edgedetection
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffStripByteCounts = 279
+	tiffTileWidth       = 322
+	tiffSampleFormat    = 339
+)
+
+// readTiffDepth reads the first image of an uncompressed, single channel
+// TIFF file with 32 or 64-bit float or 16-bit unsigned samples in strips.
+func readTiffDepth(r io.Reader) (*DepthMap, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("tiff: header is too short")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	tags, err := readTiffTags(data, order, int(order.Uint32(data[4:])))
+	if err != nil {
+		return nil, err
+	}
+
+	value := func(tag, fallback int) int {
+		if v, ok := tags[tag]; ok && len(v) > 0 {
+			return v[0]
+		}
+		return fallback
+	}
+	if _, tiled := tags[tiffTileWidth]; tiled {
+		return nil, fmt.Errorf("tiff: tiled images are not supported")
+	}
+	if value(tiffCompression, 1) != 1 {
+		return nil, fmt.Errorf("tiff: compressed images are not supported")
+	}
+	if value(tiffSamplesPerPixel, 1) != 1 {
+		return nil, fmt.Errorf("tiff: depth must have one sample per pixel")
+	}
+
+	var kind string
+	switch [2]int{value(tiffSampleFormat, 1), value(tiffBitsPerSample, 1)} {
+	case [2]int{3, 32}:
+		kind = "float32"
+	case [2]int{3, 64}:
+		kind = "float64"
+	case [2]int{1, 16}:
+		kind = "uint16"
+	default:
+		return nil, fmt.Errorf("tiff: unsupported sample type")
+	}
+	size := map[string]int{"uint16": 2, "float32": 4, "float64": 8}[kind]
+
+	depth := newDepthMap(value(tiffImageWidth, 0), value(tiffImageLength, 0))
+	offsets, counts := tags[tiffStripOffsets], tags[tiffStripByteCounts]
+	if depth.Width <= 0 || depth.Height <= 0 || len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil, fmt.Errorf("tiff: missing image size or strips")
+	}
+
+	samples := make([]byte, 0, len(depth.Values)*size)
+	for i, offset := range offsets {
+		if offset < 0 || counts[i] < 0 || offset+counts[i] > len(data) {
+			return nil, fmt.Errorf("tiff: strip %d exceeds the file", i)
+		}
+		samples = append(samples, data[offset:offset+counts[i]]...)
+	}
+	if len(samples) < len(depth.Values)*size {
+		return nil, fmt.Errorf("tiff: strips are shorter than the image")
+	}
+
+	for i := range depth.Values {
+		depth.Values[i] = decodeSample(samples[i*size:], kind, order)
+	}
+	return depth, nil
+}
+
+// readTiffTags reads the SHORT and LONG values of the first IFD.
+func readTiffTags(data []byte, order binary.ByteOrder, offset int) (map[int][]int, error) {
+	if offset < 0 || offset+2 > len(data) {
+		return nil, fmt.Errorf("tiff: invalid IFD offset")
+	}
+	count := int(order.Uint16(data[offset:]))
+	if offset+2+12*count > len(data) {
+		return nil, fmt.Errorf("tiff: IFD exceeds the file")
+	}
+
+	tags := make(map[int][]int)
+	for i := 0; i < count; i++ {
+		entry := data[offset+2+12*i:]
+		tag := int(order.Uint16(entry))
+		kind := order.Uint16(entry[2:])
+		n := int(order.Uint32(entry[4:]))
+
+		size := 0
+		switch kind {
+		case 3:
+			size = 2
+		case 4:
+			size = 4
+		default:
+			continue
+		}
+
+		values := entry[8:12]
+		if n*size > 4 {
+			start := int(order.Uint32(entry[8:]))
+			if n < 0 || start < 0 || start+n*size > len(data) {
+				return nil, fmt.Errorf("tiff: tag %d exceeds the file", tag)
+			}
+			values = data[start : start+n*size]
+		}
+
+		tags[tag] = make([]int, n)
+		for j := range tags[tag] {
+			if size == 2 {
+				tags[tag][j] = int(order.Uint16(values[2*j:]))
+			} else {
+				tags[tag][j] = int(order.Uint32(values[4*j:]))
+			}
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,39 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package edgedetection
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// WindowSink shows every debug image in a window and waits
+// for a key press before the detection continues.
+type WindowSink struct {
+	// Width and Height of the window, 1024 if 0
+	Width  int
+	Height int
+}
+
+// Image ...
+func (s WindowSink) Image(name string, img image.Image) error {
+	mat, err := gocv.ImageToMatRGB(img)
+	if err != nil {
+		return err
+	}
+	defer mat.Close()
+
+	width, height := s.Width, s.Height
+	if width == 0 || height == 0 {
+		width, height = 1024, 1024
+	}
+
+	window := gocv.NewWindow(name)
+	defer window.Close()
+	window.IMShow(mat)
+	window.ResizeWindow(width, height)
+	window.WaitKey(0)
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package edgedetection
+
+import (
+	"errors"
+	"image"
+)
+
+// WindowSink needs OpenCV, without cgo every image is rejected.
+type WindowSink struct {
+	Width  int
+	Height int
+}
+
+// Image ...
+func (s WindowSink) Image(name string, img image.Image) error {
+	return errors.New("windows need the gocv backend, build with cgo")
+}
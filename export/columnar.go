@@ -0,0 +1,230 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/edgeDetection/hdbscan"
+)
+
+// The columnar format stores every value of a point attribute in one
+// flate compressed block. It starts with the magic "HDBC", a uint32
+// version, a uint64 number of rows and a uint32 number of columns.
+// Every column is a uint16 name length, the name, a type byte and a
+// uint64 length of the compressed little endian values.
+const (
+	columnarMagic   = "HDBC"
+	columnarVersion = 1
+
+	columnFloat64 = 1
+	columnInt32   = 2
+	columnFloat32 = 3
+)
+
+// Column is a single attribute of a columnar file.
+// Only the slice matching the type of the column is set.
+type Column struct {
+	Name    string
+	Float64 []float64
+	Float32 []float32
+	Int32   []int32
+}
+
+// WriteColumnar writes the coordinates (x, y, z or c0..cn), label,
+// probability and outlier_score as compressed columns.
+func WriteColumnar(w io.Writer, c *hdbscan.Clustering, coords [][]float64) error {
+	var dim int
+	if len(coords) > 0 {
+		dim = len(coords[0])
+	}
+
+	r, err := newResult(c, coords, dim)
+	if err != nil {
+		return err
+	}
+
+	var columns []Column
+	for j, name := range coordinateNames(dim) {
+		values := make([]float64, len(coords))
+		for i, p := range coords {
+			values[i] = p[j]
+		}
+		columns = append(columns, Column{Name: name, Float64: values})
+	}
+
+	labels := make([]int32, len(coords))
+	probabilities := make([]float32, len(coords))
+	scores := make([]float32, len(coords))
+	for i := range coords {
+		labels[i] = int32(r.labels[i])
+		probabilities[i] = float32(r.probabilities[i])
+		scores[i] = float32(r.outlierScores[i])
+	}
+	columns = append(columns,
+		Column{Name: "label", Int32: labels},
+		Column{Name: "probability", Float32: probabilities},
+		Column{Name: "outlier_score", Float32: scores},
+	)
+
+	return writeColumns(w, len(coords), columns)
+}
+
+func writeColumns(w io.Writer, rows int, columns []Column) error {
+	writer := bufio.NewWriter(w)
+	header := make([]byte, 0, 20)
+	header = append(header, columnarMagic...)
+	header = appendUint32(header, columnarVersion)
+	header = appendUint64(header, uint64(rows))
+	header = appendUint32(header, uint32(len(columns)))
+	_, err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		kind, raw := encodeColumn(column)
+
+		var compressed bytes.Buffer
+		fw, err := flate.NewWriter(&compressed, flate.BestSpeed)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(raw)
+		if err != nil {
+			return err
+		}
+		err = fw.Close()
+		if err != nil {
+			return err
+		}
+
+		descriptor := make([]byte, 0, 2+len(column.Name)+1+8)
+		descriptor = append(descriptor, byte(len(column.Name)), byte(len(column.Name)>>8))
+		descriptor = append(descriptor, column.Name...)
+		descriptor = append(descriptor, kind)
+		descriptor = appendUint64(descriptor, uint64(compressed.Len()))
+		_, err = writer.Write(descriptor)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(compressed.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// ReadColumnar reads all columns of a file written by WriteColumnar.
+func ReadColumnar(r io.Reader) ([]Column, error) {
+	header := make([]byte, 20)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != columnarMagic || binary.LittleEndian.Uint32(header[4:]) != columnarVersion {
+		return nil, ErrFormat
+	}
+	rows := int(binary.LittleEndian.Uint64(header[8:]))
+	count := int(binary.LittleEndian.Uint32(header[16:]))
+
+	columns := make([]Column, 0, count)
+	for k := 0; k < count; k++ {
+		nameLength := make([]byte, 2)
+		_, err = io.ReadFull(r, nameLength)
+		if err != nil {
+			return nil, err
+		}
+		rest := make([]byte, int(binary.LittleEndian.Uint16(nameLength))+1+8)
+		_, err = io.ReadFull(r, rest)
+		if err != nil {
+			return nil, err
+		}
+		name := string(rest[:len(rest)-9])
+		kind := rest[len(rest)-9]
+		size := int64(binary.LittleEndian.Uint64(rest[len(rest)-8:]))
+
+		raw, err := ioutil.ReadAll(flate.NewReader(io.LimitReader(r, size)))
+		if err != nil {
+			return nil, err
+		}
+
+		column, err := decodeColumn(name, kind, raw, rows)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+func encodeColumn(column Column) (byte, []byte) {
+	switch {
+	case column.Int32 != nil:
+		raw := make([]byte, 0, 4*len(column.Int32))
+		for _, v := range column.Int32 {
+			raw = appendUint32(raw, uint32(v))
+		}
+		return columnInt32, raw
+	case column.Float32 != nil:
+		raw := make([]byte, 0, 4*len(column.Float32))
+		for _, v := range column.Float32 {
+			raw = appendUint32(raw, math.Float32bits(v))
+		}
+		return columnFloat32, raw
+	}
+
+	raw := make([]byte, 0, 8*len(column.Float64))
+	for _, v := range column.Float64 {
+		raw = appendUint64(raw, math.Float64bits(v))
+	}
+	return columnFloat64, raw
+}
+
+func decodeColumn(name string, kind byte, raw []byte, rows int) (Column, error) {
+	column := Column{Name: name}
+	switch kind {
+	case columnInt32:
+		if len(raw) != 4*rows {
+			return column, ErrFormat
+		}
+		column.Int32 = make([]int32, rows)
+		for i := range column.Int32 {
+			column.Int32[i] = int32(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+	case columnFloat32:
+		if len(raw) != 4*rows {
+			return column, ErrFormat
+		}
+		column.Float32 = make([]float32, rows)
+		for i := range column.Float32 {
+			column.Float32[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+	case columnFloat64:
+		if len(raw) != 8*rows {
+			return column, ErrFormat
+		}
+		column.Float64 = make([]float64, rows)
+		for i := range column.Float64 {
+			column.Float64[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[8*i:]))
+		}
+	default:
+		return column, ErrFormat
+	}
+	return column, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/edgeDetection/hdbscan"
+)
+
+// WriteCSV writes one row per point with the coordinates,
+// label, probability and outlier_score columns.
+// The coordinates may have any number of dimensions,
+// three dimensional coordinates are named x, y and z.
+func WriteCSV(w io.Writer, c *hdbscan.Clustering, coords [][]float64) error {
+	var dim int
+	if len(coords) > 0 {
+		dim = len(coords[0])
+	}
+
+	r, err := newResult(c, coords, dim)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := append(coordinateNames(dim), "label", "probability", "outlier_score")
+
+	err = writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	row := make([]string, dim+3)
+	for i, p := range r.coords {
+		for j, v := range p {
+			row[j] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		row[dim] = strconv.Itoa(r.labels[i])
+		row[dim+1] = strconv.FormatFloat(r.probabilities[i], 'f', -1, 64)
+		row[dim+2] = strconv.FormatFloat(r.outlierScores[i], 'f', -1, 64)
+
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
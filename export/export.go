@@ -0,0 +1,62 @@
+// Package export writes clustering results together
+// with the coordinates of the clustered points.
+package export
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgeDetection/hdbscan"
+)
+
+var (
+	// ErrLength ...
+	ErrLength = errors.New("number of coordinates does not match the clustering")
+	// ErrDimension ...
+	ErrDimension = errors.New("coordinates must have three dimensions")
+	// ErrFormat ...
+	ErrFormat = errors.New("invalid file format")
+)
+
+// result holds the per point values of a clustering.
+type result struct {
+	coords        [][]float64
+	labels        []int
+	probabilities []float64
+	outlierScores []float64
+}
+
+func newResult(c *hdbscan.Clustering, coords [][]float64, dim int) (*result, error) {
+	if len(coords) != c.Len() {
+		return nil, ErrLength
+	}
+
+	if dim > 0 {
+		for _, p := range coords {
+			if len(p) != dim {
+				return nil, ErrDimension
+			}
+		}
+	}
+
+	return &result{
+		coords:        coords,
+		labels:        c.Labels(),
+		probabilities: c.Probabilities(),
+		outlierScores: c.OutlierScores(),
+	}, nil
+}
+
+// coordinateNames returns x, y and z for three dimensional
+// coordinates and c0..cn otherwise.
+func coordinateNames(dim int) []string {
+	if dim == 3 {
+		return []string{"x", "y", "z"}
+	}
+
+	names := make([]string, dim)
+	for j := range names {
+		names[j] = fmt.Sprintf("c%d", j)
+	}
+	return names
+}
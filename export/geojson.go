@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/edgeDetection/hdbscan"
+)
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONPoint      `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	Label        int     `json:"label"`
+	Probability  float64 `json:"probability"`
+	OutlierScore float64 `json:"outlier_score"`
+}
+
+// WriteGeoJSON writes all points as a GeoJSON FeatureCollection of
+// Point features with the properties label, probability and outlier_score.
+// The coordinates must have two or three dimensions, they are written
+// as they are without any transformation.
+func WriteGeoJSON(w io.Writer, c *hdbscan.Clustering, coords [][]float64) error {
+	var dim int
+	if len(coords) > 0 {
+		dim = len(coords[0])
+	}
+	if dim != 0 && dim != 2 && dim != 3 {
+		return ErrDimension
+	}
+
+	r, err := newResult(c, coords, dim)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+	_, err = writer.WriteString(`{"type":"FeatureCollection","features":[`)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range r.coords {
+		feature, err := json.Marshal(geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONPoint{Type: "Point", Coordinates: p},
+			Properties: geoJSONProperties{
+				Label:        r.labels[i],
+				Probability:  r.probabilities[i],
+				OutlierScore: r.outlierScores[i],
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			err = writer.WriteByte(',')
+			if err != nil {
+				return err
+			}
+		}
+		_, err = writer.Write(feature)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = writer.WriteString("]}\n")
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/edgeDetection/hdbscan"
+)
+
+// LAS classification codes
+const (
+	lasUnclassified = 1
+	lasNoise        = 7
+)
+
+// LAS 1.2 header with point data record format 0.
+type lasHeader struct {
+	FileSignature             [4]byte
+	FileSourceID              uint16
+	GlobalEncoding            uint16
+	GUID                      [16]byte
+	VersionMajor              uint8
+	VersionMinor              uint8
+	SystemIdentifier          [32]byte
+	GeneratingSoftware        [32]byte
+	CreationDay               uint16
+	CreationYear              uint16
+	HeaderSize                uint16
+	OffsetToPointData         uint32
+	NumberOfVLRs              uint32
+	PointDataFormat           uint8
+	PointDataRecordLength     uint16
+	NumberOfPoints            uint32
+	NumberOfPointsByReturn    [5]uint32
+	ScaleX, ScaleY, ScaleZ    float64
+	OffsetX, OffsetY, OffsetZ float64
+	MaxX, MinX                float64
+	MaxY, MinY                float64
+	MaxZ, MinZ                float64
+}
+
+type lasPoint struct {
+	X, Y, Z        int32
+	Intensity      uint16
+	ReturnFlags    uint8
+	Classification uint8
+	ScanAngle      int8
+	UserData       uint8
+	PointSourceID  uint16
+}
+
+// WriteLAS writes all points as LAS 1.2 point records (format 0)
+// with a resolution of `scale` (e.g. 0.001 for millimeters).
+// Noise points are classified as noise (7), clustered points as
+// unclassified (1). The point source id holds the cluster label + 1
+// (0 for noise) and the user data the outlier score scaled to 0-255.
+func WriteLAS(w io.Writer, c *hdbscan.Clustering, coords [][]float64, scale float64) error {
+	r, err := newResult(c, coords, 3)
+	if err != nil {
+		return err
+	}
+
+	if scale <= 0 {
+		scale = 0.001
+	}
+
+	header := lasHeader{
+		FileSignature:         [4]byte{'L', 'A', 'S', 'F'},
+		VersionMajor:          1,
+		VersionMinor:          2,
+		CreationDay:           uint16(time.Now().YearDay()),
+		CreationYear:          uint16(time.Now().Year()),
+		PointDataFormat:       0,
+		PointDataRecordLength: uint16(binary.Size(lasPoint{})),
+		NumberOfPoints:        uint32(len(coords)),
+		ScaleX:                scale,
+		ScaleY:                scale,
+		ScaleZ:                scale,
+		MinX:                  math.MaxFloat64,
+		MinY:                  math.MaxFloat64,
+		MinZ:                  math.MaxFloat64,
+		MaxX:                  -math.MaxFloat64,
+		MaxY:                  -math.MaxFloat64,
+		MaxZ:                  -math.MaxFloat64,
+	}
+	copy(header.SystemIdentifier[:], "hdbscan")
+	copy(header.GeneratingSoftware[:], "hdbscan export")
+	header.HeaderSize = uint16(binary.Size(header))
+	header.OffsetToPointData = uint32(header.HeaderSize)
+	header.NumberOfPointsByReturn[0] = header.NumberOfPoints
+
+	for _, p := range coords {
+		header.MinX, header.MaxX = math.Min(header.MinX, p[0]), math.Max(header.MaxX, p[0])
+		header.MinY, header.MaxY = math.Min(header.MinY, p[1]), math.Max(header.MaxY, p[1])
+		header.MinZ, header.MaxZ = math.Min(header.MinZ, p[2]), math.Max(header.MaxZ, p[2])
+	}
+	if len(coords) > 0 {
+		header.OffsetX, header.OffsetY, header.OffsetZ = header.MinX, header.MinY, header.MinZ
+	}
+
+	writer := bufio.NewWriter(w)
+	err = binary.Write(writer, binary.LittleEndian, header)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range coords {
+		point := lasPoint{
+			X:              int32(math.Round((p[0] - header.OffsetX) / scale)),
+			Y:              int32(math.Round((p[1] - header.OffsetY) / scale)),
+			Z:              int32(math.Round((p[2] - header.OffsetZ) / scale)),
+			ReturnFlags:    1<<3 | 1, // return 1 of 1
+			Classification: lasUnclassified,
+			UserData:       uint8(math.Round(r.outlierScores[i] * 255)),
+			PointSourceID:  uint16(r.labels[i] + 1),
+		}
+		if r.labels[i] < 0 {
+			point.Classification = lasNoise
+		}
+
+		err = binary.Write(writer, binary.LittleEndian, point)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
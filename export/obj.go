@@ -0,0 +1,21 @@
+package export
+
+import (
+	"io"
+
+	"github.com/edgeDetection/edgedetection"
+	"github.com/edgeDetection/hdbscan"
+)
+
+// WriteLabelledOBJ writes the mesh of the detection with one group
+// and material per cluster. The clustering must have been computed on
+// the samples of the detection, every sample is mapped back to the
+// face it was extracted from.
+// The materials are written to mtl, mtlFile is the name the obj file refers to.
+func WriteLabelledOBJ(obj, mtl io.Writer, mtlFile string, d *edgedetection.Data, c *hdbscan.Clustering) error {
+	if c.Len() != len(d.Faces) {
+		return ErrLength
+	}
+
+	return d.WriteLabelledObj(obj, mtl, mtlFile, d.FaceLabels(c.Labels()))
+}
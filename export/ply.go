@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/edgeDetection/hdbscan"
+)
+
+// WritePLY writes all points as PLY vertices with the properties
+// x, y, z, label, probability and outlier_score.
+// Noise points have the label -1.
+func WritePLY(w io.Writer, c *hdbscan.Clustering, coords [][]float64, binaryFormat bool) error {
+	r, err := newResult(c, coords, 3)
+	if err != nil {
+		return err
+	}
+
+	format := "ascii"
+	if binaryFormat {
+		format = "binary_little_endian"
+	}
+
+	writer := bufio.NewWriter(w)
+	_, err = fmt.Fprintf(writer, "ply\nformat %s 1.0\ncomment hdbscan clustering\nelement vertex %d\n"+
+		"property double x\nproperty double y\nproperty double z\n"+
+		"property int label\nproperty float probability\nproperty float outlier_score\nend_header\n",
+		format, len(coords))
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, 3*8+3*4)
+	for i, p := range r.coords {
+		if !binaryFormat {
+			_, err = fmt.Fprintf(writer, "%f %f %f %d %f %f\n", p[0], p[1], p[2], r.labels[i], r.probabilities[i], r.outlierScores[i])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		binary.LittleEndian.PutUint64(record[0:], math.Float64bits(p[0]))
+		binary.LittleEndian.PutUint64(record[8:], math.Float64bits(p[1]))
+		binary.LittleEndian.PutUint64(record[16:], math.Float64bits(p[2]))
+		binary.LittleEndian.PutUint32(record[24:], uint32(int32(r.labels[i])))
+		binary.LittleEndian.PutUint32(record[28:], math.Float32bits(float32(r.probabilities[i])))
+		binary.LittleEndian.PutUint32(record[32:], math.Float32bits(float32(r.outlierScores[i])))
+		_, err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/edgeDetection/edgedetection"
+	"github.com/edgeDetection/geometry"
+	"github.com/edgeDetection/hdbscan"
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// ClusterShape describes the geometry of one cluster.
+type ClusterShape struct {
+	Label  int
+	Points int
+	// Box is axis aligned, OrientedBox along the principal axes
+	Box         geometry.Box
+	OrientedBox geometry.OrientedBox
+	Hull        geometry.Hull
+	// Area is the summed area of the distinct faces of the cluster
+	Area     float64
+	Features geometry.EigenFeatures
+	// Normal is the dominant normal weighted by face area
+	Normal mgl64.Vec3
+}
+
+// ClusterShapes computes the shape of every cluster from the samples of
+// the detection, the clustering must have been computed on them.
+// The shapes are in the order of Clusters, the label is the cluster index.
+func ClusterShapes(d *edgedetection.Data, c *hdbscan.Clustering) ([]ClusterShape, error) {
+	samples := d.Samples()
+	normals := d.Normals()
+	if c.Len() != len(samples) || c.Len() != len(normals) || c.Len() != len(d.Faces) {
+		return nil, ErrLength
+	}
+
+	shapes := make([]ClusterShape, len(c.Clusters))
+	for label, cluster := range c.Clusters {
+		points := make([]mgl64.Vec3, len(cluster.Points))
+		clusterNormals := make([]mgl64.Vec3, len(cluster.Points))
+		weights := make([]float64, len(cluster.Points))
+		faces := make(map[int]bool)
+		area := 0.
+		for i, p := range cluster.Points {
+			points[i] = mgl64.Vec3{samples[p][0], samples[p][1], samples[p][2]}
+
+			clusterNormals[i] = mgl64.Vec3{normals[p][0], normals[p][1], normals[p][2]}
+
+			face := d.Faces[p]
+			weights[i] = d.FaceArea(face)
+			if !faces[face] {
+				faces[face] = true
+				area += weights[i]
+			}
+		}
+
+		shapes[label] = ClusterShape{
+			Label:       label,
+			Points:      len(points),
+			Box:         geometry.BoundingBox(points),
+			OrientedBox: geometry.PrincipalBox(points),
+			Hull:        geometry.ConvexHull(points),
+			Area:        area,
+			Features:    geometry.Features(points),
+			Normal:      geometry.DominantNormal(clusterNormals, weights),
+		}
+	}
+	return shapes, nil
+}
+
+type shapeJSON struct {
+	Label       int             `json:"label"`
+	Points      int             `json:"points"`
+	Box         boxJSON         `json:"box"`
+	OrientedBox orientedBoxJSON `json:"orientedBox"`
+	Hull        hullJSON        `json:"hull"`
+	Area        float64         `json:"area"`
+	Linearity   float64         `json:"linearity"`
+	Planarity   float64         `json:"planarity"`
+	Sphericity  float64         `json:"sphericity"`
+	Normal      [3]float64      `json:"normal"`
+}
+
+type boxJSON struct {
+	Min [3]float64 `json:"min"`
+	Max [3]float64 `json:"max"`
+}
+
+type orientedBoxJSON struct {
+	Center   [3]float64    `json:"center"`
+	Axes     [3][3]float64 `json:"axes"`
+	HalfSize [3]float64    `json:"halfSize"`
+	Corners  [8][3]float64 `json:"corners"`
+}
+
+type hullJSON struct {
+	Vertices [][3]float64 `json:"vertices"`
+	Faces    [][3]int     `json:"faces"`
+	Area     float64      `json:"area"`
+	Volume   float64      `json:"volume"`
+}
+
+// WriteShapesJSON writes the cluster shapes as a JSON array.
+func WriteShapesJSON(w io.Writer, shapes []ClusterShape) error {
+	out := make([]shapeJSON, len(shapes))
+	for i, s := range shapes {
+		o := shapeJSON{
+			Label:      s.Label,
+			Points:     s.Points,
+			Box:        boxJSON{Min: s.Box.Min, Max: s.Box.Max},
+			Area:       s.Area,
+			Linearity:  s.Features.Linearity,
+			Planarity:  s.Features.Planarity,
+			Sphericity: s.Features.Sphericity,
+			Normal:     s.Normal,
+		}
+
+		o.OrientedBox = orientedBoxJSON{
+			Center:   s.OrientedBox.Center,
+			HalfSize: s.OrientedBox.HalfSize,
+		}
+		for j, axis := range s.OrientedBox.Axes {
+			o.OrientedBox.Axes[j] = axis
+		}
+		for j, corner := range s.OrientedBox.Corners() {
+			o.OrientedBox.Corners[j] = corner
+		}
+
+		o.Hull = hullJSON{
+			Vertices: make([][3]float64, len(s.Hull.Vertices)),
+			Faces:    s.Hull.Faces,
+			Area:     s.Hull.Area(),
+			Volume:   s.Hull.Volume(),
+		}
+		for j, v := range s.Hull.Vertices {
+			o.Hull.Vertices[j] = v
+		}
+		if o.Hull.Faces == nil {
+			o.Hull.Faces = [][3]int{}
+		}
+		out[i] = o
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
@@ -0,0 +1,76 @@
+package export
+
+import (
+	"encoding/json"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"github.com/edgeDetection/edgedetection"
+	"github.com/edgeDetection/hdbscan"
+)
+
+// Footprints projects the clusters into the texture of the detection.
+// The clustering must have been computed on the samples of the detection.
+func Footprints(d *edgedetection.Data, c *hdbscan.Clustering) ([]edgedetection.ClusterFootprint, error) {
+	if c.Len() != len(d.Faces) {
+		return nil, ErrLength
+	}
+	return d.ClusterFootprints(c.Labels())
+}
+
+type footprintsJSON struct {
+	Width    int             `json:"width"`
+	Height   int             `json:"height"`
+	Clusters []footprintJSON `json:"clusters"`
+}
+
+type footprintJSON struct {
+	Label  int `json:"label"`
+	Faces  int `json:"faces"`
+	Pixels int `json:"pixels"`
+	// Box is x0, y0, x1, y1 with exclusive x1 and y1
+	Box  [4]int   `json:"box"`
+	Hull [][2]int `json:"hull"`
+}
+
+// WriteFootprintsJSON writes the texture size and the label, face and
+// pixel count, bounding box and convex hull of every footprint as JSON.
+func WriteFootprintsJSON(w io.Writer, d *edgedetection.Data, footprints []edgedetection.ClusterFootprint) error {
+	bounds := d.TextureBounds()
+	out := footprintsJSON{
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		Clusters: make([]footprintJSON, len(footprints)),
+	}
+	for i, f := range footprints {
+		hull := make([][2]int, len(f.Hull))
+		for j, p := range f.Hull {
+			hull[j] = [2]int{p.X, p.Y}
+		}
+		out.Clusters[i] = footprintJSON{
+			Label:  f.Label,
+			Faces:  f.Faces,
+			Pixels: f.Pixels,
+			Box:    [4]int{f.Bounds.Min.X, f.Bounds.Min.Y, f.Bounds.Max.X, f.Bounds.Max.Y},
+			Hull:   hull,
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// WriteOverlayPNG writes the texture with the footprints drawn on it.
+func WriteOverlayPNG(w io.Writer, d *edgedetection.Data, footprints []edgedetection.ClusterFootprint) error {
+	return png.Encode(w, d.ClusterOverlay(footprints))
+}
+
+// WriteMaskPNG writes the mask of a footprint in the size of the texture.
+func WriteMaskPNG(w io.Writer, d *edgedetection.Data, f edgedetection.ClusterFootprint) error {
+	mask := image.NewGray(d.TextureBounds())
+	draw.Draw(mask, f.Bounds, f.Mask, f.Bounds.Min, draw.Src)
+	return png.Encode(w, mask)
+}
@@ -0,0 +1,222 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Hull is a convex hull. Faces index Vertices and are counter
+// clockwise seen from outside.
+type Hull struct {
+	Vertices []mgl64.Vec3
+	Faces    [][3]int
+}
+
+// hullFace is a face of the hull under construction.
+type hullFace struct {
+	v     [3]int
+	plane Plane
+}
+
+// ConvexHull returns the convex hull of the points with an incremental
+// algorithm. Coplanar points give a flat hull of the triangulated polygon
+// facing one side, collinear points a hull with the two end points
+// and no faces.
+func ConvexHull(points []mgl64.Vec3) Hull {
+	if len(points) < 3 {
+		return Hull{Vertices: append([]mgl64.Vec3(nil), points...)}
+	}
+
+	box := BoundingBox(points)
+	eps := 1e-9 * math.Max(1, box.Size().Len())
+
+	// initial simplex of extreme points
+	i0 := 0
+	for i, p := range points {
+		if p.X() < points[i0].X() {
+			i0 = i
+		}
+	}
+	i1 := farthest(points, func(p mgl64.Vec3) float64 { return p.Sub(points[i0]).Len() })
+	line := points[i1].Sub(points[i0]).Normalize()
+	i2 := farthest(points, func(p mgl64.Vec3) float64 { return p.Sub(points[i0]).Cross(line).Len() })
+	if points[i2].Sub(points[i0]).Cross(line).Len() <= eps {
+		return Hull{Vertices: []mgl64.Vec3{points[i0], points[i1]}}
+	}
+	base, _ := PlaneFromPoints(points[i0], points[i1], points[i2])
+	i3 := farthest(points, func(p mgl64.Vec3) float64 { return math.Abs(base.Distance(p)) })
+	if math.Abs(base.Distance(points[i3])) <= eps {
+		return planarHull(points, base, line)
+	}
+
+	var faces []hullFace
+	edges := make(map[[2]int]int)
+	addFace := func(a, b, c int) {
+		plane, _ := PlaneFromPoints(points[a], points[b], points[c])
+		faces = append(faces, hullFace{v: [3]int{a, b, c}, plane: plane})
+	}
+	if base.Distance(points[i3]) > 0 {
+		i1, i2 = i2, i1
+	}
+	addFace(i0, i1, i2)
+	addFace(i0, i3, i1)
+	addFace(i1, i3, i2)
+	addFace(i2, i3, i0)
+
+	for p := range points {
+		if p == i0 || p == i1 || p == i2 || p == i3 {
+			continue
+		}
+
+		visible := make([]bool, len(faces))
+		outside := false
+		for f, face := range faces {
+			if face.plane.Distance(points[p]) > eps {
+				visible[f] = true
+				outside = true
+			}
+		}
+		if !outside {
+			continue
+		}
+
+		// the horizon are the edges between visible and hidden faces
+		for k := range edges {
+			delete(edges, k)
+		}
+		for f, face := range faces {
+			for e := 0; e < 3; e++ {
+				edges[[2]int{face.v[e], face.v[(e+1)%3]}] = f
+			}
+		}
+		var kept []hullFace
+		var horizon [][2]int
+		for f, face := range faces {
+			if !visible[f] {
+				kept = append(kept, face)
+				continue
+			}
+			for e := 0; e < 3; e++ {
+				a, b := face.v[e], face.v[(e+1)%3]
+				if !visible[edges[[2]int{b, a}]] {
+					horizon = append(horizon, [2]int{a, b})
+				}
+			}
+		}
+		faces = kept
+		for _, e := range horizon {
+			addFace(e[0], e[1], p)
+		}
+	}
+
+	return compactHull(points, faces)
+}
+
+// farthest returns the index of the point with the largest distance.
+func farthest(points []mgl64.Vec3, distance func(mgl64.Vec3) float64) int {
+	best, max := 0, math.Inf(-1)
+	for i, p := range points {
+		if d := distance(p); d > max {
+			best, max = i, d
+		}
+	}
+	return best
+}
+
+// compactHull keeps only the points used by the faces.
+func compactHull(points []mgl64.Vec3, faces []hullFace) Hull {
+	var hull Hull
+	index := make(map[int]int)
+	for _, f := range faces {
+		var face [3]int
+		for i, v := range f.v {
+			n, ok := index[v]
+			if !ok {
+				n = len(hull.Vertices)
+				index[v] = n
+				hull.Vertices = append(hull.Vertices, points[v])
+			}
+			face[i] = n
+		}
+		hull.Faces = append(hull.Faces, face)
+	}
+	return hull
+}
+
+// planarHull returns the convex polygon of coplanar points,
+// triangulated as a fan facing the side of the plane normal.
+func planarHull(points []mgl64.Vec3, plane Plane, u mgl64.Vec3) Hull {
+	v := plane.Normal.Cross(u)
+	type planar struct {
+		x, y float64
+		i    int
+	}
+	projected := make([]planar, len(points))
+	for i, p := range points {
+		projected[i] = planar{p.Dot(u), p.Dot(v), i}
+	}
+	sort.Slice(projected, func(a, b int) bool {
+		if projected[a].x != projected[b].x {
+			return projected[a].x < projected[b].x
+		}
+		return projected[a].y < projected[b].y
+	})
+	cross := func(o, a, b planar) float64 {
+		return (a.x-o.x)*(b.y-o.y) - (a.y-o.y)*(b.x-o.x)
+	}
+
+	// monotone chain, counter clockwise around the normal
+	chain := make([]planar, 0, 2*len(projected))
+	for _, p := range projected {
+		for len(chain) >= 2 && cross(chain[len(chain)-2], chain[len(chain)-1], p) <= 0 {
+			chain = chain[:len(chain)-1]
+		}
+		chain = append(chain, p)
+	}
+	lower := len(chain) + 1
+	for i := len(projected) - 2; i >= 0; i-- {
+		p := projected[i]
+		for len(chain) >= lower && cross(chain[len(chain)-2], chain[len(chain)-1], p) <= 0 {
+			chain = chain[:len(chain)-1]
+		}
+		chain = append(chain, p)
+	}
+	chain = chain[:len(chain)-1]
+
+	hull := Hull{Vertices: make([]mgl64.Vec3, len(chain))}
+	for i, p := range chain {
+		hull.Vertices[i] = points[p.i]
+	}
+	for i := 1; i+1 < len(chain); i++ {
+		hull.Faces = append(hull.Faces, [3]int{0, i, i + 1})
+	}
+	return hull
+}
+
+// Area returns the surface area of the hull.
+func (h Hull) Area() float64 {
+	area := 0.
+	for _, f := range h.Faces {
+		a, b, c := h.Vertices[f[0]], h.Vertices[f[1]], h.Vertices[f[2]]
+		area += b.Sub(a).Cross(c.Sub(a)).Len() / 2
+	}
+	return area
+}
+
+// Volume returns the enclosed volume of the hull, 0 for a flat hull.
+func (h Hull) Volume() float64 {
+	if len(h.Vertices) == 0 {
+		return 0
+	}
+	origin := h.Vertices[0]
+	volume := 0.
+	for _, f := range h.Faces {
+		a := h.Vertices[f[0]].Sub(origin)
+		b := h.Vertices[f[1]].Sub(origin)
+		c := h.Vertices[f[2]].Sub(origin)
+		volume += a.Dot(b.Cross(c)) / 6
+	}
+	return math.Abs(volume)
+}
@@ -0,0 +1,98 @@
+package geometry
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"gonum.org/v1/gonum/spatial/kdtree"
+)
+
+// KDTree finds the nearest neighbours of 3D points.
+// It is safe for concurrent queries.
+type KDTree struct {
+	tree *kdtree.Tree
+}
+
+// NewKDTree builds the tree, the indexes returned by
+// its queries refer to the points slice.
+func NewKDTree(points []mgl64.Vec3) *KDTree {
+	indexed := make(indexedPoints, len(points))
+	for i, p := range points {
+		indexed[i] = indexedPoint{point: p, index: i}
+	}
+	return &KDTree{tree: kdtree.New(indexed, false)}
+}
+
+// Nearest returns the indexes of the k nearest points to q,
+// the nearest first.
+func (t *KDTree) Nearest(q mgl64.Vec3, k int) []int {
+	keeper := kdtree.NewNKeeper(k)
+	t.tree.NearestSet(keeper, indexedPoint{point: q, index: -1})
+
+	// the keeper is a max heap, sort by distance
+	neighbours := make([]kdtree.ComparableDist, 0, k)
+	for _, c := range keeper.Heap {
+		if c.Comparable != nil {
+			neighbours = append(neighbours, c)
+		}
+	}
+	for i := 1; i < len(neighbours); i++ {
+		for j := i; j > 0 && neighbours[j].Dist < neighbours[j-1].Dist; j-- {
+			neighbours[j], neighbours[j-1] = neighbours[j-1], neighbours[j]
+		}
+	}
+
+	indexes := make([]int, len(neighbours))
+	for i, c := range neighbours {
+		indexes[i] = c.Comparable.(indexedPoint).index
+	}
+	return indexes
+}
+
+type indexedPoint struct {
+	point mgl64.Vec3
+	index int
+}
+
+func (p indexedPoint) Compare(c kdtree.Comparable, d kdtree.Dim) float64 {
+	return p.point[d] - c.(indexedPoint).point[d]
+}
+
+func (p indexedPoint) Dims() int {
+	return 3
+}
+
+// Distance is the squared euclidean distance.
+func (p indexedPoint) Distance(c kdtree.Comparable) float64 {
+	d := p.point.Sub(c.(indexedPoint).point)
+	return d.Dot(d)
+}
+
+type indexedPoints []indexedPoint
+
+func (p indexedPoints) Index(i int) kdtree.Comparable { return p[i] }
+
+func (p indexedPoints) Len() int { return len(p) }
+
+func (p indexedPoints) Pivot(d kdtree.Dim) int {
+	return kdtree.Partition(indexedPlane{points: p, dim: d}, kdtree.MedianOfMedians(indexedPlane{points: p, dim: d}))
+}
+
+func (p indexedPoints) Slice(start, end int) kdtree.Interface { return p[start:end] }
+
+// indexedPlane sorts the points along one dimension.
+type indexedPlane struct {
+	points indexedPoints
+	dim    kdtree.Dim
+}
+
+func (p indexedPlane) Len() int { return len(p.points) }
+
+func (p indexedPlane) Less(i, j int) bool {
+	return p.points[i].point[p.dim] < p.points[j].point[p.dim]
+}
+
+func (p indexedPlane) Swap(i, j int) { p.points[i], p.points[j] = p.points[j], p.points[i] }
+
+func (p indexedPlane) Slice(start, end int) kdtree.SortSlicer {
+	p.points = p.points[start:end]
+	return p
+}
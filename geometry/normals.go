@@ -0,0 +1,65 @@
+package geometry
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// PointNormals estimates the normal and curvature of every query point
+// with PCA of its k nearest neighbours in the cloud. The normal is the
+// eigenvector of the smallest eigenvalue of the neighbourhood covariance,
+// oriented toward viewpoint. The curvature is the surface variation
+// λ0 / (λ0 + λ1 + λ2), 0 on a plane and 1/3 for isotropic noise.
+// Queries with less than three neighbours get a zero normal.
+func PointNormals(cloud *KDTree, points []mgl64.Vec3, queries []mgl64.Vec3, k int, viewpoint mgl64.Vec3) ([]mgl64.Vec3, []float64) {
+	normals := make([]mgl64.Vec3, len(queries))
+	curvature := make([]float64, len(queries))
+
+	workers := runtime.NumCPU()
+	chunk := (len(queries) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(queries); start += chunk {
+		end := minInt(start+chunk, len(queries))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			neighbourhood := make([]mgl64.Vec3, 0, k)
+			for i := start; i < end; i++ {
+				neighbourhood = neighbourhood[:0]
+				for _, n := range cloud.Nearest(queries[i], k) {
+					neighbourhood = append(neighbourhood, points[n])
+				}
+				normals[i], curvature[i] = pcaNormal(neighbourhood, queries[i], viewpoint)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	return normals, curvature
+}
+
+func pcaNormal(neighbourhood []mgl64.Vec3, point, viewpoint mgl64.Vec3) (mgl64.Vec3, float64) {
+	if len(neighbourhood) < 3 {
+		return mgl64.Vec3{}, 0
+	}
+
+	_, covariance := Covariance(neighbourhood)
+	values, vectors, ok := eigenSym(covariance)
+	if !ok {
+		return mgl64.Vec3{}, 0
+	}
+
+	normal := vectors[0]
+	if normal.Dot(viewpoint.Sub(point)) < 0 {
+		normal = normal.Mul(-1)
+	}
+
+	curvature := 0.0
+	if sum := values[0] + values[1] + values[2]; sum > 0 {
+		curvature = math.Max(0, values[0]) / sum
+	}
+	return normal, curvature
+}
@@ -0,0 +1,115 @@
+// Package geometry fits geometric primitives to point clouds.
+package geometry
+
+import (
+	"errors"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"gonum.org/v1/gonum/mat"
+)
+
+// ErrNoPlane is returned if no plane can be fitted to the points.
+var ErrNoPlane = errors.New("no plane found")
+
+// Plane is the plane Normal·p + D = 0 with a unit normal.
+type Plane struct {
+	Normal mgl64.Vec3
+	D      float64
+}
+
+// Distance returns the signed distance of a point,
+// positive on the side the normal points to.
+func (p Plane) Distance(point mgl64.Vec3) float64 {
+	return p.Normal.Dot(point) + p.D
+}
+
+// Orient flips the plane so that its normal points to the side of up.
+func (p Plane) Orient(up mgl64.Vec3) Plane {
+	if p.Normal.Dot(up) < 0 {
+		return Plane{Normal: p.Normal.Mul(-1), D: -p.D}
+	}
+	return p
+}
+
+// PlaneFromPoints returns the plane through three points,
+// false if they are collinear.
+func PlaneFromPoints(a, b, c mgl64.Vec3) (Plane, bool) {
+	normal := b.Sub(a).Cross(c.Sub(a))
+	length := normal.Len()
+	if length < 1e-12 {
+		return Plane{}, false
+	}
+	normal = normal.Mul(1 / length)
+	return Plane{Normal: normal, D: -normal.Dot(a)}, true
+}
+
+// FitPlane returns the least squares plane through the points, its normal
+// is the eigenvector of the smallest eigenvalue of their covariance.
+func FitPlane(points []mgl64.Vec3) (Plane, error) {
+	if len(points) < 3 {
+		return Plane{}, ErrNoPlane
+	}
+
+	centroid, covariance := Covariance(points)
+	values, vectors, ok := eigenSym(covariance)
+	if !ok || values[1] <= 0 {
+		return Plane{}, ErrNoPlane
+	}
+
+	normal := vectors[0]
+	return Plane{Normal: normal, D: -normal.Dot(centroid)}, nil
+}
+
+// Covariance returns the centroid and the covariance matrix of the points.
+func Covariance(points []mgl64.Vec3) (mgl64.Vec3, mgl64.Mat3) {
+	var centroid mgl64.Vec3
+	for _, p := range points {
+		centroid = centroid.Add(p)
+	}
+	centroid = centroid.Mul(1 / float64(len(points)))
+
+	var covariance mgl64.Mat3
+	for _, p := range points {
+		d := p.Sub(centroid)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				covariance[3*j+i] += d[i] * d[j]
+			}
+		}
+	}
+	return centroid, covariance.Mul(1 / float64(len(points)))
+}
+
+// eigenSym returns the eigenvalues of a symmetric matrix in ascending
+// order and their unit eigenvectors.
+func eigenSym(m mgl64.Mat3) ([3]float64, [3]mgl64.Vec3, bool) {
+	var values [3]float64
+	var vectors [3]mgl64.Vec3
+
+	sym := mat.NewSymDense(3, nil)
+	for i := 0; i < 3; i++ {
+		for j := i; j < 3; j++ {
+			sym.SetSym(i, j, m.At(i, j))
+		}
+	}
+
+	var eigen mat.EigenSym
+	if !eigen.Factorize(sym, true) {
+		return values, vectors, false
+	}
+	var ev mat.Dense
+	eigen.VectorsTo(&ev)
+	for i, v := range eigen.Values(nil) {
+		values[i] = v
+		vectors[i] = mgl64.Vec3{ev.At(0, i), ev.At(1, i), ev.At(2, i)}.Normalize()
+	}
+	return values, vectors, true
+}
+
+// angle returns the angle between two unit vectors in degrees,
+// ignoring their orientation.
+func angle(a, b mgl64.Vec3) float64 {
+	cos := math.Min(1, math.Abs(a.Dot(b)))
+	return mgl64.RadToDeg(math.Acos(cos))
+}
@@ -0,0 +1,178 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Ransac holds the parameters of a RANSAC plane fit.
+type Ransac struct {
+	// Iterations is the maximum number of sampled planes
+	Iterations int
+	// Tolerance is the maximum distance of an inlier
+	Tolerance float64
+	// Confidence stops early once a better plane is unlikely, 0 never stops
+	Confidence float64
+	// SampleSize limits the points used to score a plane, 0 uses all
+	SampleSize int
+	// Up and MaxTilt only accept planes whose normal is at most
+	// MaxTilt degrees away from Up, MaxTilt 0 accepts all planes
+	Up      mgl64.Vec3
+	MaxTilt float64
+	// MaxNormalAngle only counts points as inliers whose normal is at
+	// most MaxNormalAngle degrees away from the plane normal, if normals
+	// are given; 0 ignores the normals
+	MaxNormalAngle float64
+}
+
+// DefaultRansac returns 1000 iterations with 2cm tolerance.
+func DefaultRansac() Ransac {
+	return Ransac{
+		Iterations: 1000,
+		Tolerance:  0.02,
+		Confidence: 0.99,
+		SampleSize: 50000,
+	}
+}
+
+// Fit finds the plane with the most inliers and refits it to its inliers
+// with least squares until the inliers do not change anymore.
+// It returns the plane and the indexes of its inliers.
+func (r Ransac) Fit(points []mgl64.Vec3, random *rand.Rand) (Plane, []int, error) {
+	return r.FitNormals(points, nil, random)
+}
+
+// FitNormals is Fit for points with normals, which are compared
+// to the plane normal if MaxNormalAngle is set. normals may be nil.
+func (r Ransac) FitNormals(points, normals []mgl64.Vec3, random *rand.Rand) (Plane, []int, error) {
+	if len(points) < 3 {
+		return Plane{}, nil, ErrNoPlane
+	}
+	if r.MaxNormalAngle <= 0 {
+		normals = nil
+	}
+
+	scoring := make([]int, len(points))
+	if r.SampleSize > 0 && len(points) > r.SampleSize {
+		scoring = scoring[:r.SampleSize]
+		for i := range scoring {
+			scoring[i] = random.Intn(len(points))
+		}
+	} else {
+		for i := range scoring {
+			scoring[i] = i
+		}
+	}
+
+	var best Plane
+	bestCount := 0
+	iterations := r.Iterations
+	for i := 0; i < iterations; i++ {
+		plane, ok := PlaneFromPoints(
+			points[scoring[random.Intn(len(scoring))]],
+			points[scoring[random.Intn(len(scoring))]],
+			points[scoring[random.Intn(len(scoring))]])
+		if !ok || !r.accept(plane) {
+			continue
+		}
+
+		count := 0
+		for _, i := range scoring {
+			if r.inlier(plane, points, normals, i) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = plane, count
+			iterations = minInt(r.Iterations, r.requiredIterations(count, len(scoring)))
+		}
+	}
+	if bestCount < 3 {
+		return Plane{}, nil, ErrNoPlane
+	}
+
+	return r.refit(points, normals, best)
+}
+
+// requiredIterations is the number of samples needed to draw three
+// inliers with the confidence, given the current inlier ratio.
+func (r Ransac) requiredIterations(inliers, total int) int {
+	if r.Confidence <= 0 || r.Confidence >= 1 {
+		return r.Iterations
+	}
+	w := math.Pow(float64(inliers)/float64(total), 3)
+	if w >= 1 {
+		return 0
+	}
+	n := math.Log(1-r.Confidence) / math.Log(1-w)
+	if math.IsNaN(n) || n > float64(r.Iterations) {
+		return r.Iterations
+	}
+	return int(math.Ceil(n))
+}
+
+func (r Ransac) accept(plane Plane) bool {
+	return r.MaxTilt <= 0 || angle(plane.Normal, r.Up.Normalize()) <= r.MaxTilt
+}
+
+// refit fits the plane to its inliers until they are stable. A refitted
+// plane that tilts more than MaxTilt is dropped and the previous one kept.
+func (r Ransac) refit(points, normals []mgl64.Vec3, plane Plane) (Plane, []int, error) {
+	inliers := r.inliers(points, normals, plane)
+	for i := 0; i < 10; i++ {
+		selected := make([]mgl64.Vec3, len(inliers))
+		for j, index := range inliers {
+			selected[j] = points[index]
+		}
+		refitted, err := FitPlane(selected)
+		if err != nil || !r.accept(refitted) {
+			break
+		}
+
+		next := r.inliers(points, normals, refitted)
+		if len(next) < len(inliers) {
+			break
+		}
+		stable := len(next) == len(inliers)
+		plane, inliers = refitted, next
+		if stable {
+			break
+		}
+	}
+
+	if r.Up != (mgl64.Vec3{}) {
+		plane = plane.Orient(r.Up)
+	}
+	return plane, inliers, nil
+}
+
+// Inliers returns the indexes of the points within the tolerance of the plane.
+func (r Ransac) Inliers(points []mgl64.Vec3, plane Plane) []int {
+	return r.inliers(points, nil, plane)
+}
+
+func (r Ransac) inliers(points, normals []mgl64.Vec3, plane Plane) []int {
+	inliers := make([]int, 0)
+	for i := range points {
+		if r.inlier(plane, points, normals, i) {
+			inliers = append(inliers, i)
+		}
+	}
+	return inliers
+}
+
+func (r Ransac) inlier(plane Plane, points, normals []mgl64.Vec3, i int) bool {
+	if math.Abs(plane.Distance(points[i])) > r.Tolerance {
+		return false
+	}
+	return normals == nil || angle(plane.Normal, normals[i]) <= r.MaxNormalAngle
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,70 @@
+package geometry
+
+import (
+	"math/rand"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Segment is a plane with the indexes of its inliers.
+type Segment struct {
+	Plane   Plane
+	Inliers []int
+}
+
+// Segment extracts up to k planes with sequential RANSAC: the plane
+// with the most inliers is found, its inliers are removed and the
+// search is repeated on the remaining points. It stops early if a
+// plane has less than minInliers inliers. normals may be nil.
+// It returns the planes and the indexes of the points on no plane.
+func (r Ransac) Segment(points, normals []mgl64.Vec3, k, minInliers int, random *rand.Rand) ([]Segment, []int) {
+	remaining := make([]int, len(points))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	segments := make([]Segment, 0, k)
+	for len(segments) < k && len(remaining) >= maxInt(3, minInliers) {
+		subset := make([]mgl64.Vec3, len(remaining))
+		var subsetNormals []mgl64.Vec3
+		if normals != nil {
+			subsetNormals = make([]mgl64.Vec3, len(remaining))
+		}
+		for i, index := range remaining {
+			subset[i] = points[index]
+			if normals != nil {
+				subsetNormals[i] = normals[index]
+			}
+		}
+
+		plane, inliers, err := r.FitNormals(subset, subsetNormals, random)
+		if err != nil || len(inliers) < minInliers {
+			break
+		}
+
+		segment := Segment{Plane: plane, Inliers: make([]int, len(inliers))}
+		onPlane := make([]bool, len(remaining))
+		for i, inlier := range inliers {
+			segment.Inliers[i] = remaining[inlier]
+			onPlane[inlier] = true
+		}
+		segments = append(segments, segment)
+
+		rest := remaining[:0]
+		for i, index := range remaining {
+			if !onPlane[i] {
+				rest = append(rest, index)
+			}
+		}
+		remaining = rest
+	}
+
+	return segments, remaining
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,165 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Box is an axis aligned bounding box.
+type Box struct {
+	Min mgl64.Vec3
+	Max mgl64.Vec3
+}
+
+// BoundingBox returns the axis aligned bounding box of the points.
+func BoundingBox(points []mgl64.Vec3) Box {
+	if len(points) == 0 {
+		return Box{}
+	}
+	box := Box{Min: points[0], Max: points[0]}
+	for _, p := range points[1:] {
+		for i := 0; i < 3; i++ {
+			box.Min[i] = math.Min(box.Min[i], p[i])
+			box.Max[i] = math.Max(box.Max[i], p[i])
+		}
+	}
+	return box
+}
+
+// Size returns the edge lengths of the box.
+func (b Box) Size() mgl64.Vec3 {
+	return b.Max.Sub(b.Min)
+}
+
+// OrientedBox is a bounding box along the principal axes of the points.
+// Axes are unit vectors sorted by descending variance and form a right
+// handed frame, HalfSize holds the half edge length along every axis.
+type OrientedBox struct {
+	Center   mgl64.Vec3
+	Axes     [3]mgl64.Vec3
+	HalfSize mgl64.Vec3
+}
+
+// PrincipalBox returns the bounding box of the points along the
+// eigenvectors of their covariance.
+func PrincipalBox(points []mgl64.Vec3) OrientedBox {
+	if len(points) == 0 {
+		return OrientedBox{}
+	}
+
+	axes := [3]mgl64.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	_, covariance := Covariance(points)
+	if _, vectors, ok := eigenSym(covariance); ok {
+		axes = [3]mgl64.Vec3{vectors[2], vectors[1], vectors[2].Cross(vectors[1])}
+	}
+
+	var low, high mgl64.Vec3
+	for i := 0; i < 3; i++ {
+		low[i] = math.Inf(1)
+		high[i] = math.Inf(-1)
+	}
+	for _, p := range points {
+		for i, axis := range axes {
+			d := axis.Dot(p)
+			low[i] = math.Min(low[i], d)
+			high[i] = math.Max(high[i], d)
+		}
+	}
+
+	box := OrientedBox{Axes: axes}
+	for i, axis := range axes {
+		box.Center = box.Center.Add(axis.Mul((low[i] + high[i]) / 2))
+		box.HalfSize[i] = (high[i] - low[i]) / 2
+	}
+	return box
+}
+
+// Corners returns the eight corners of the box.
+func (b OrientedBox) Corners() [8]mgl64.Vec3 {
+	var corners [8]mgl64.Vec3
+	for c := range corners {
+		corner := b.Center
+		for i, axis := range b.Axes {
+			sign := 1.
+			if c&(1<<uint(i)) == 0 {
+				sign = -1
+			}
+			corner = corner.Add(axis.Mul(sign * b.HalfSize[i]))
+		}
+		corners[c] = corner
+	}
+	return corners
+}
+
+// EigenFeatures are the shape features of a point set from the
+// eigenvalues λ1 ≥ λ2 ≥ λ3 of its covariance (Weinmann et al., 2015).
+// Linearity, planarity and sphericity add up to 1.
+type EigenFeatures struct {
+	// Linearity (λ1 - λ2) / λ1 is large for lines
+	Linearity float64
+	// Planarity (λ2 - λ3) / λ1 is large for planes
+	Planarity float64
+	// Sphericity λ3 / λ1 is large for volumes
+	Sphericity float64
+}
+
+// Features returns the eigen features of the points,
+// all zero for less than two distinct points.
+func Features(points []mgl64.Vec3) EigenFeatures {
+	if len(points) < 2 {
+		return EigenFeatures{}
+	}
+	_, covariance := Covariance(points)
+	values, _, ok := eigenSym(covariance)
+	if !ok {
+		return EigenFeatures{}
+	}
+
+	l1 := values[2]
+	l2 := math.Max(0, values[1])
+	l3 := math.Max(0, values[0])
+	if l1 <= 0 {
+		return EigenFeatures{}
+	}
+	return EigenFeatures{
+		Linearity:  (l1 - l2) / l1,
+		Planarity:  (l2 - l3) / l1,
+		Sphericity: l3 / l1,
+	}
+}
+
+// DominantNormal returns the axis most normals are parallel to,
+// the eigenvector of the largest eigenvalue of Σ w·n·nᵀ. It points to
+// the side of the weighted normal sum. weights may be nil, no
+// normals give a zero vector.
+func DominantNormal(normals []mgl64.Vec3, weights []float64) mgl64.Vec3 {
+	if len(normals) == 0 {
+		return mgl64.Vec3{}
+	}
+
+	var scatter mgl64.Mat3
+	var sum mgl64.Vec3
+	for k, n := range normals {
+		w := 1.
+		if weights != nil {
+			w = weights[k]
+		}
+		sum = sum.Add(n.Mul(w))
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				scatter[3*j+i] += w * n[i] * n[j]
+			}
+		}
+	}
+
+	_, vectors, ok := eigenSym(scatter)
+	if !ok {
+		return mgl64.Vec3{}
+	}
+	normal := vectors[2]
+	if normal.Dot(sum) < 0 {
+		normal = normal.Mul(-1)
+	}
+	return normal
+}
@@ -0,0 +1,233 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"log"
+	"math"
+	"sort"
+)
+
+// defaultBlockSize is the number of rows that are
+// read from a DataSource at once.
+const defaultBlockSize = 4096
+
+// blockedReachability computes the core distances and the minimum spanning
+// tree of the mutual reachability graph block by block from the source.
+// Only two blocks of rows, the k nearest distances of one block and a
+// constant number of values per point are held in memory, the
+// lambda matrix is never built.
+// The time is not reduced: every pass evaluates the distance function for
+// all N² pairs, once for the core distances and once per Boruvka round.
+// There is no spatial index because the distance function is arbitrary.
+func (c *Clustering) blockedReachability() (edges, error) {
+	if c.verbose {
+		log.Println("starting blocked mutual reachability, block size: ", c.blockSize)
+	}
+
+	coreDistances, err := c.blockedCoreDistances()
+	if err != nil {
+		return nil, err
+	}
+	c.coreDistances = coreDistances
+
+	tree, err := c.blockedMinSpanningTree()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.verbose {
+		log.Println("finished blocked mutual reachability")
+	}
+
+	return tree, nil
+}
+
+// forEachBlockPair reads the source in blocks and calls fn for
+// every combination of an outer and an inner block.
+func (c *Clustering) forEachBlockPair(fn func(outerStart int, outer [][]float64, innerStart int, inner [][]float64)) error {
+	length := c.source.Len()
+	dim := c.source.Dim()
+	outerBlock := newBlock(c.blockSize, dim)
+	innerBlock := newBlock(c.blockSize, dim)
+
+	for outerStart := 0; outerStart < length; outerStart += c.blockSize {
+		outer := outerBlock[:minInt(c.blockSize, length-outerStart)]
+		err := c.source.ReadRows(outerStart, outer)
+		if err != nil {
+			return err
+		}
+
+		for innerStart := 0; innerStart < length; innerStart += c.blockSize {
+			inner := innerBlock[:minInt(c.blockSize, length-innerStart)]
+			err = c.source.ReadRows(innerStart, inner)
+			if err != nil {
+				return err
+			}
+
+			fn(outerStart, outer, innerStart, inner)
+		}
+	}
+
+	return nil
+}
+
+// blockedCoreDistances keeps the `mcs` smallest distances of every
+// point of the outer block while streaming over all inner blocks.
+func (c *Clustering) blockedCoreDistances() ([]float64, error) {
+	coreDistances := make([]float64, c.source.Len())
+	var nearest [][]float64
+
+	err := c.forEachBlockPair(func(outerStart int, outer [][]float64, innerStart int, inner [][]float64) {
+		if innerStart == 0 {
+			nearest = make([][]float64, len(outer))
+		}
+
+		for i := range outer {
+			c.wg.Add(1)
+			c.semaphore <- true
+			go func(i int) {
+				for _, p := range inner {
+					nearest[i] = insertNearest(nearest[i], c.distanceFunc(outer[i], p), c.mcs)
+				}
+				<-c.semaphore
+				c.wg.Done()
+			}(i)
+		}
+		c.wg.Wait()
+
+		if innerStart+len(inner) == c.source.Len() {
+			for i, n := range nearest {
+				coreDistances[outerStart+i] = n[c.mcs-1]
+			}
+		}
+	})
+
+	return coreDistances, err
+}
+
+// insertNearest inserts a distance into an ascending list
+// that keeps at most k values.
+func insertNearest(nearest []float64, dist float64, k int) []float64 {
+	if len(nearest) == k && dist >= nearest[k-1] {
+		return nearest
+	}
+
+	index := sort.SearchFloat64s(nearest, dist)
+	if len(nearest) < k {
+		nearest = append(nearest, 0)
+	}
+	copy(nearest[index+1:], nearest[index:])
+	nearest[index] = dist
+	return nearest
+}
+
+// blockedMinSpanningTree builds the minimum spanning tree with Boruvka's
+// algorithm. Every round streams over all block pairs once to find the
+// shortest edge leaving every component, so the number of passes over
+// the source grows only logarithmically with the number of points,
+// at most log2(N) rounds and usually a handful.
+func (c *Clustering) blockedMinSpanningTree() (edges, error) {
+	length := c.source.Len()
+	components := newUnionFind(length)
+	tree := make(edges, 0, length-1)
+
+	// shortest outgoing edge per point and per component
+	pointBest := make([]edge, length)
+	componentBest := make([]edge, length)
+	root := make([]int, length)
+
+	for len(tree) < length-1 {
+		for i := range pointBest {
+			pointBest[i] = edge{dist: math.Inf(1)}
+			componentBest[i] = edge{dist: math.Inf(1)}
+			root[i] = components.find(i)
+		}
+
+		err := c.forEachBlockPair(func(outerStart int, outer [][]float64, innerStart int, inner [][]float64) {
+			for i := range outer {
+				c.wg.Add(1)
+				c.semaphore <- true
+				go func(i int) {
+					p1 := outerStart + i
+					for j, p := range inner {
+						p2 := innerStart + j
+						if root[p1] == root[p2] {
+							continue
+						}
+						e := edge{
+							p1:   p1,
+							p2:   p2,
+							dist: math.Max(math.Max(c.coreDistances[p1], c.coreDistances[p2]), c.distanceFunc(outer[i], p)),
+						}
+						if lessEdge(e, pointBest[p1]) {
+							pointBest[p1] = e
+						}
+					}
+					<-c.semaphore
+					c.wg.Done()
+				}(i)
+			}
+			c.wg.Wait()
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for p, e := range pointBest {
+			if lessEdge(e, componentBest[root[p]]) {
+				componentBest[root[p]] = e
+			}
+		}
+
+		added := 0
+		for r, e := range componentBest {
+			if r != root[r] || math.IsInf(e.dist, 1) {
+				continue
+			}
+			if components.union(e.p1, e.p2) {
+				tree = append(tree, e)
+				added++
+			}
+		}
+
+		if c.verbose {
+			log.Println("boruvka round, edges in tree: ", len(tree))
+		}
+
+		// the graph is not connected
+		if added == 0 {
+			break
+		}
+	}
+
+	sort.Stable(tree)
+	return tree, nil
+}
+
+// lessEdge orders edges by distance and breaks ties by the point
+// indexes, which keeps Boruvka's algorithm free of cycles.
+func lessEdge(a, b edge) bool {
+	if a.dist != b.dist {
+		return a.dist < b.dist
+	}
+
+	a1, a2 := minInt(a.p1, a.p2), maxInt(a.p1, a.p2)
+	b1, b2 := minInt(b.p1, b.p2), maxInt(b.p1, b.p2)
+	if a1 != b1 {
+		return a1 < b1
+	}
+	return a2 < b2
+}
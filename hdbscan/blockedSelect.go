@@ -0,0 +1,367 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"log"
+	"math"
+)
+
+// selectCondensed extracts the flat clustering of a DataSource clustering
+// from the condensed tree. Variances, centroids and outliers are computed
+// while streaming over the blocks of the source, the rows are never
+// held in memory at once.
+func (c *Clustering) selectCondensed(score string) error {
+	c.buildCondensedClusters()
+
+	if c.verbose {
+		log.Println("score clusters")
+	}
+
+	switch score {
+	case VarianceScore:
+		c.setNormalizedSizes()
+		err := c.blockedVariances()
+		if err != nil {
+			return err
+		}
+		c.Clusters.setVarianceScores()
+	case Leaf:
+		c.leafScore()
+	case StabilityScore:
+		c.condensedStabilityScores()
+	}
+
+	c.selectOptimalClustering(score)
+	labels := c.assignCondensedPoints()
+
+	err := c.blockedCentroids(labels)
+	if err != nil {
+		return err
+	}
+
+	err = c.blockedOutliersAndVoronoi(labels)
+	if err != nil {
+		return err
+	}
+
+	c.outlierClustering()
+
+	return nil
+}
+
+// forEachBlock reads the source in blocks and calls fn for every block.
+func (c *Clustering) forEachBlock(fn func(start int, block [][]float64)) error {
+	length := c.source.Len()
+	block := newBlock(c.blockSize, c.source.Dim())
+
+	for start := 0; start < length; start += c.blockSize {
+		rows := block[:minInt(c.blockSize, length-start)]
+		err := c.source.ReadRows(start, rows)
+		if err != nil {
+			return err
+		}
+
+		fn(start, rows)
+	}
+
+	return nil
+}
+
+// blockedVariances sets the generalized variance of every node of the
+// condensed tree. The sums of the coordinates and of their outer products
+// are collected in the node every point falls out of and then added to
+// the parents, so the source is read only once.
+func (c *Clustering) blockedVariances() error {
+	dim := c.source.Dim()
+	nodes := c.condensed.nodes
+	sums := make([][]float64, len(nodes))
+	products := make([][]float64, len(nodes))
+	for n := range nodes {
+		sums[n] = make([]float64, dim)
+		products[n] = make([]float64, dim*dim)
+	}
+
+	// coordinates are taken relative to the first row
+	// to keep the sums small
+	var origin []float64
+	err := c.forEachBlock(func(start int, block [][]float64) {
+		if origin == nil {
+			origin = append([]float64(nil), block[0]...)
+		}
+
+		for i, row := range block {
+			n := c.condensed.pointNode[start+i]
+			if n < 0 {
+				continue
+			}
+
+			for j := range row {
+				dj := row[j] - origin[j]
+				sums[n][j] += dj
+				for k := range row {
+					products[n][j*dim+k] += dj * (row[k] - origin[k])
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// parents have larger indexes than their children
+	for n, node := range nodes {
+		if node.parent < 0 {
+			continue
+		}
+		for j := range sums[n] {
+			sums[node.parent][j] += sums[n][j]
+		}
+		for j := range products[n] {
+			products[node.parent][j] += products[n][j]
+		}
+	}
+
+	for _, cluster := range c.Clusters {
+		variance := generalizedVarianceOfSums(cluster.count, sums[cluster.id], products[cluster.id])
+		cluster.variance = isNum(variance)
+	}
+
+	return nil
+}
+
+// blockedCentroids sets the centroid of every selected cluster.
+func (c *Clustering) blockedCentroids(labels []int) error {
+	if c.verbose {
+		log.Println("calculating cluster centroids")
+	}
+
+	for _, cluster := range c.Clusters {
+		cluster.Centroid = make([]float64, c.dim())
+	}
+
+	err := c.forEachBlock(func(start int, block [][]float64) {
+		for i, row := range block {
+			label := labels[start+i]
+			if label < 0 {
+				continue
+			}
+			for j, v := range row {
+				c.Clusters[label].Centroid[j] += v
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, cluster := range c.Clusters {
+		for j := range cluster.Centroid {
+			cluster.Centroid[j] /= float64(len(cluster.Points))
+		}
+	}
+
+	if c.verbose {
+		log.Println("finished calculating cluster centroids")
+	}
+
+	return nil
+}
+
+// blockedOutliersAndVoronoi assigns the points that are in no cluster to
+// their nearest cluster as `outliersAndVoronoi` does. The labels are
+// updated with the points added by the voronoi option.
+func (c *Clustering) blockedOutliersAndVoronoi(labels []int) error {
+	if !c.od && !c.voronoi {
+		return nil
+	}
+
+	if len(c.Clusters) == 0 {
+		return nil
+	}
+
+	if c.verbose {
+		log.Println("finding nearest clusters of unclustered points")
+	}
+
+	nearest, distances, err := c.blockedNearestClusters(labels)
+	if err != nil {
+		return err
+	}
+
+	for p, label := range labels {
+		if label >= 0 {
+			continue
+		}
+
+		cluster := c.Clusters[nearest[p]]
+		// voronoi cluster
+		if c.voronoi {
+			cluster.Points = append(cluster.Points, p)
+			labels[p] = nearest[p]
+		}
+
+		// outlier detection
+		if c.od {
+			cluster.Outliers = append(cluster.Outliers, Outlier{
+				Index:              p,
+				NormalizedDistance: distances[p],
+			})
+		}
+	}
+
+	// normalize outlier distances
+	if c.od {
+		err = c.blockedDistanceDistributions(labels)
+		if err != nil {
+			return err
+		}
+		c.Clusters.normalizeOutliers()
+	}
+
+	if c.verbose {
+		log.Println("finished finding nearest clusters of unclustered points")
+	}
+
+	return nil
+}
+
+// blockedNearestClusters returns for every point without a label the
+// index of the nearest cluster and the distance to it, measured to the
+// centroid or with the nearest neighbor option to the nearest point.
+func (c *Clustering) blockedNearestClusters(labels []int) ([]int, []float64, error) {
+	nearest := make([]int, len(labels))
+	distances := make([]float64, len(labels))
+	for p := range distances {
+		distances[p] = math.MaxFloat64
+	}
+
+	if !c.nn {
+		err := c.forEachBlock(func(start int, block [][]float64) {
+			for i, row := range block {
+				p := start + i
+				if labels[p] >= 0 {
+					continue
+				}
+				for j, cluster := range c.Clusters {
+					distance := c.distanceFunc(cluster.Centroid, row)
+					if distance < distances[p] {
+						distances[p] = distance
+						nearest[p] = j
+					}
+				}
+			}
+		})
+		return nearest, distances, err
+	}
+
+	err := c.forEachBlockPair(func(outerStart int, outer [][]float64, innerStart int, inner [][]float64) {
+		for i := range outer {
+			if labels[outerStart+i] >= 0 {
+				continue
+			}
+
+			c.wg.Add(1)
+			c.semaphore <- true
+			go func(i int) {
+				p := outerStart + i
+				for j, row := range inner {
+					label := labels[innerStart+j]
+					if label < 0 {
+						continue
+					}
+					distance := c.distanceFunc(row, outer[i])
+					if distance < distances[p] {
+						distances[p] = distance
+						nearest[p] = label
+					}
+				}
+				<-c.semaphore
+				c.wg.Done()
+			}(i)
+		}
+		c.wg.Wait()
+	})
+
+	return nearest, distances, err
+}
+
+// blockedDistanceDistributions fits the distance distribution of every
+// cluster as `distanceDistributions` does.
+func (c *Clustering) blockedDistanceDistributions(labels []int) error {
+	distances := make([][]float64, len(c.Clusters))
+
+	if !c.nn {
+		err := c.forEachBlock(func(start int, block [][]float64) {
+			for i, row := range block {
+				label := labels[start+i]
+				if label < 0 {
+					continue
+				}
+				distances[label] = append(distances[label], c.distanceFunc(row, c.Clusters[label].Centroid))
+			}
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		// nearest neighbor distance of every point within its cluster
+		minDistances := make([]float64, len(labels))
+		for p := range minDistances {
+			minDistances[p] = math.MaxFloat64
+		}
+
+		err := c.forEachBlockPair(func(outerStart int, outer [][]float64, innerStart int, inner [][]float64) {
+			for i := range outer {
+				if labels[outerStart+i] < 0 {
+					continue
+				}
+
+				c.wg.Add(1)
+				c.semaphore <- true
+				go func(i int) {
+					p := outerStart + i
+					for j, row := range inner {
+						if innerStart+j == p || labels[innerStart+j] != labels[p] {
+							continue
+						}
+						distance := c.distanceFunc(outer[i], row)
+						if distance < minDistances[p] {
+							minDistances[p] = distance
+						}
+					}
+					<-c.semaphore
+					c.wg.Done()
+				}(i)
+			}
+			c.wg.Wait()
+		})
+		if err != nil {
+			return err
+		}
+
+		for p, label := range labels {
+			if label >= 0 {
+				distances[label] = append(distances[label], minDistances[p])
+			}
+		}
+	}
+
+	for i, cluster := range c.Clusters {
+		cluster.setDistanceDistribution(distances[i])
+	}
+
+	return nil
+}
@@ -16,8 +16,10 @@ package hdbscan
 
 import (
 	"log"
+	"math/rand"
 	"runtime"
 	"sync"
+	"time"
 
 	"gonum.org/v1/gonum/stat/distuv"
 )
@@ -40,10 +42,12 @@ type cluster struct {
 	children             []int
 	score                float64
 	delta                int
+	count                int
 	size                 float64
 	variance             float64
 	lMin                 float64
 	lambdaBirth          float64
+	dist                 float64
 	distanceDistribution *distuv.Normal
 	largestDistance      float64
 	viseted              bool
@@ -70,6 +74,8 @@ type Outliers []Outlier
 // all final results.
 type Clustering struct {
 	data      [][]float64
+	matrix    *Matrix
+	source    DataSource
 	directory string
 
 	// settings
@@ -83,10 +89,29 @@ type Clustering struct {
 	od           bool // Outlier detection
 	oc           bool // Outlier Clustering
 	sampleBound  int
+	blockSize    int
+	epsilon      float64
 	distanceFunc DistanceFunc
 
+	// distance function for float32 matrix rows
+	distanceFunc32 DistanceFunc32
+
+	// random decisions, set by Seed or RandSource
+	random *rand.Rand
+	// indexes of the sampled data points, see Subsample and RandomSample
+	sampleIndexes []int
+
 	// minimum spanning tree
 	mst *tree
+	// exact minimum spanning tree of `CutAt` for data that is not
+	// read from a DataSource, built on the first cut
+	cutTree edges
+
+	// hierarchy built by `Fit`
+	dendogram     []*link
+	condensed     *condensedTree
+	coreDistances []float64
+	selectOptions SelectOptions
 
 	// optimal-clustering
 	score            string
@@ -96,8 +121,9 @@ type Clustering struct {
 	lambda           [][]float64
 
 	// Multithreading
-	semaphore chan bool
-	wg        *sync.WaitGroup
+	semaphore     chan bool
+	wg            *sync.WaitGroup
+	deterministic bool
 }
 
 // NewClustering creates (a pointer to) a new clustering struct.
@@ -131,15 +157,126 @@ func NewClustering(data [][]float64, minimumClusterSize int, directory string) (
 	}, nil
 }
 
+// SelectOptions holds the settings used by `Select`
+// to extract a flat clustering from a fitted hierarchy.
+type SelectOptions struct {
+	// Score is the selection method:
+	// `StabilityScore`, `VarianceScore` or `Leaf`.
+	Score string
+	// Epsilon is a distance threshold. Clusters that split
+	// off below this distance are merged back into their
+	// nearest ancestor that was born at or above it.
+	// Zero disables the threshold.
+	Epsilon float64
+
+	OutlierDetection  bool
+	OutlierClustering bool
+	Voronoi           bool
+	NearestNeighbor   bool
+}
+
+// NewSourceClustering creates (a pointer to) a new clustering struct
+// for data that is read from a DataSource.
+// `Fit` computes the core distances and the minimum spanning tree
+// block by block without building the lambda matrix, `Select` works on
+// the condensed tree and reads the rows block by block as well.
+func NewSourceClustering(source DataSource, minimumClusterSize int, directory string) (*Clustering, error) {
+	if minimumClusterSize < 1 {
+		return &Clustering{}, ErrMCS
+	}
+
+	if source.Len() < minimumClusterSize {
+		return &Clustering{}, ErrDataLen
+	}
+
+	return &Clustering{
+		source:    source,
+		directory: directory,
+		mcs:       minimumClusterSize,
+		blockSize: defaultBlockSize,
+		mst:       newTree(),
+		semaphore: make(chan bool, runtime.NumCPU()),
+		wg:        &sync.WaitGroup{},
+	}, nil
+}
+
+// NewMatrixClustering creates (a pointer to) a new clustering struct
+// for data in a flat Matrix. The rows of a float64 matrix are used
+// without copying them, a float32 matrix is kept as it is and
+// requires a DistanceFunc32 set by `Distance32`.
+func NewMatrixClustering(m *Matrix, minimumClusterSize int, directory string) (*Clustering, error) {
+	if minimumClusterSize < 1 {
+		return &Clustering{}, ErrMCS
+	}
+
+	if m.Len() < minimumClusterSize {
+		return &Clustering{}, ErrDataLen
+	}
+
+	c := &Clustering{
+		matrix:    m,
+		directory: directory,
+		mcs:       minimumClusterSize,
+		mst:       newTree(),
+		semaphore: make(chan bool, runtime.NumCPU()),
+		wg:        &sync.WaitGroup{},
+	}
+
+	if !m.IsFloat32() {
+		c.data = m.rowViews()
+	}
+
+	return c, nil
+}
+
 // Run will run the clustering.
+// It is equal to calling `Fit` followed by `Select`
+// with the options set on the clustering.
 func (c *Clustering) Run(distanceFunc DistanceFunc, score string, mst bool) error {
+	err := c.Fit(distanceFunc, mst)
+	if err != nil {
+		return err
+	}
+
+	return c.Select(SelectOptions{
+		Score:             score,
+		Epsilon:           c.epsilon,
+		OutlierDetection:  c.od,
+		OutlierClustering: c.oc,
+		Voronoi:           c.voronoi,
+		NearestNeighbor:   c.nn,
+	})
+}
+
+// Fit will run the expensive stages of the clustering:
+// the mutual reachability graph, the minimum spanning tree
+// and the dendogram. The hierarchy is kept on the clustering
+// so that `Select` can be called repeatedly with different settings.
+func (c *Clustering) Fit(distanceFunc DistanceFunc, mst bool) error {
 	c.distanceFunc = distanceFunc
 	c.minTree = mst
 	if c.verbose && !c.minTree {
 		log.Println("not using minimum spanning tree")
 	}
 
-	// Will not be used right now
+	c.mst = newTree()
+	c.cutTree = nil
+
+	if c.matrix != nil && c.matrix.IsFloat32() && c.distanceFunc32 == nil {
+		return ErrDistance32
+	}
+
+	if c.source != nil {
+		edges, err := c.blockedReachability()
+		if err != nil {
+			return err
+		}
+		c.mst.edges = edges
+		c.condensed = c.condenseTree(edges)
+		return nil
+	}
+
+	// Reduce the data to the sample
 	c.sample()
 	// Calculate "Mutual Reachability Graph" and build minimum spaning tree
 	edges := c.mutualReachabilityGraph()
@@ -148,16 +285,40 @@ func (c *Clustering) Run(distanceFunc DistanceFunc, score string, mst bool) erro
 	// Plot minimum spanning tree
 	// c.plotminimumSpanningTree(edges)
 	// Build dendogram
-	dendogram := c.buildDendogram(edges)
+	c.dendogram = c.buildDendogram(edges)
+
+	return nil
+}
+
+// Select will extract a flat clustering from the hierarchy
+// built by `Fit`. It replaces `Clusters` with the new result
+// and can be called any number of times.
+func (c *Clustering) Select(opts SelectOptions) error {
+	if c.dendogram == nil && c.condensed == nil {
+		return ErrNotFitted
+	}
+
+	c.selectOptions = opts
+	c.epsilon = opts.Epsilon
+	c.od = opts.OutlierDetection || opts.OutlierClustering
+	c.oc = opts.OutlierClustering
+	c.voronoi = opts.Voronoi
+	c.nn = opts.NearestNeighbor
+	c.score = opts.Score
+
+	if c.condensed != nil {
+		return c.selectCondensed(opts.Score)
+	}
+
 	// Build Clusters
-	c.buildClusters(dendogram)
+	c.buildClusters(c.dendogram)
 
 	// Calculate stability
-	c.scoreClusters(score)
+	c.scoreClusters(opts.Score)
 	// Write Clusters to file before selecting the clusters
 	// c.writeClusterToFile("before")
 	// Select Clusters
-	c.selectOptimalClustering(score)
+	c.selectOptimalClustering(opts.Score)
 	// Write Clusters to file after selecting the clusters
 	// c.writeClusterToFile("after")
 	// Calculate centroids for every cluster
@@ -189,7 +350,9 @@ func (c *Clustering) buildClusters(links []*link) {
 
 			newCluster := &cluster{
 				id:       i,
-				Points:   link.points,
+				dist:     link.dist,
+				count:    len(link.points),
+				Points:   append([]int(nil), link.points...),
 				Outliers: make(Outliers, 0),
 				children: children,
 			}
@@ -228,26 +391,90 @@ func (c *Clustering) clusterCentroids() {
 	}
 
 	for i, cluster := range c.Clusters {
-		avg := make([]float64, len(c.data[0]), len(c.data[0]))
-		for _, index := range cluster.Points {
-			vec := c.data[index]
-			if len(vec) == len(avg) {
-				for j, v := range vec {
-					avg[j] += v
-				}
+		cluster.Centroid = c.centroid(cluster.Points)
+		c.Clusters[i] = cluster
+	}
+
+	if c.verbose {
+		log.Println("finished calculating cluster centroids")
+	}
+}
+
+func (c *Clustering) centroid(points []int) []float64 {
+	avg := make([]float64, c.dim(), c.dim())
+	for _, index := range points {
+		if c.matrix != nil {
+			c.matrix.addRow(index, avg)
+			continue
+		}
+		vec := c.data[index]
+		if len(vec) == len(avg) {
+			for j, v := range vec {
+				avg[j] += v
 			}
 		}
+	}
 
-		for k, v := range avg {
-			v /= float64(len(cluster.Points))
-			avg[k] = v
-		}
+	for k, v := range avg {
+		v /= float64(len(points))
+		avg[k] = v
+	}
 
-		cluster.Centroid = avg
-		c.Clusters[i] = cluster
+	return avg
+}
+
+// rng returns the random generator of the clustering.
+// A generator seeded with the current time is used if no seed was set.
+func (c *Clustering) rng() *rand.Rand {
+	if c.random == nil {
+		c.random = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
+	return c.random
+}
 
-	if c.verbose {
-		log.Println("finished calculating cluster centroids")
+// length returns the number of data points.
+func (c *Clustering) length() int {
+	if c.data == nil && c.matrix != nil {
+		return c.matrix.Len()
+	}
+	if c.data == nil && c.source != nil {
+		return c.source.Len()
+	}
+	return len(c.data)
+}
+
+// dim returns the length of a data point.
+func (c *Clustering) dim() int {
+	if c.matrix != nil {
+		return c.matrix.Dim()
+	}
+	if c.source != nil {
+		return c.source.Dim()
+	}
+	return len(c.data[0])
+}
+
+// point returns data point i.
+func (c *Clustering) point(i int) []float64 {
+	if c.data == nil && c.matrix != nil {
+		return c.matrix.Row(i)
+	}
+	return c.data[i]
+}
+
+// pointInto is point without allocation, a
+// float32 row is converted into buf.
+func (c *Clustering) pointInto(i int, buf []float64) []float64 {
+	if c.data == nil && c.matrix != nil {
+		return c.matrix.rowInto(i, buf)
+	}
+	return c.data[i]
+}
+
+// distance returns the distance between data point i and j.
+func (c *Clustering) distance(i, j int) float64 {
+	if c.data == nil && c.matrix != nil {
+		return c.distanceFunc32(c.matrix.Row32(i), c.matrix.Row32(j))
 	}
+	return c.distanceFunc(c.data[i], c.data[j])
 }
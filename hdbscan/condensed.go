@@ -0,0 +1,276 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"log"
+	"math"
+)
+
+// condensedTree is the cluster hierarchy of a DataSource clustering.
+// It is built directly from the sorted edges of the minimum spanning tree:
+// components that are smaller than the minimum cluster size are no
+// clusters, their points fall out of the cluster they join. Every point
+// is stored once with the node it falls out of, so the tree needs
+// O(N) memory instead of a list of points per link.
+type condensedTree struct {
+	nodes []condensedNode
+	// node every point falls out of, -1 if the point never
+	// belongs to a cluster, and the lambda at which it falls out
+	pointNode   []int
+	pointLambda []float64
+}
+
+// condensedNode is a cluster of the condensed tree. Nodes are stored
+// bottom up, the children of a node always have smaller indexes.
+type condensedNode struct {
+	parent   int
+	children []int
+	size     int
+	// distance of the edge that formed the node,
+	// the node splits into its children at this distance
+	dist float64
+}
+
+// condenseTree builds the condensed tree from the edges sorted by distance.
+// A union-find keeps the size and the node of every component, the
+// points of a component are only listed while it is smaller than the
+// minimum cluster size.
+func (c *Clustering) condenseTree(sorted edges) *condensedTree {
+	if c.verbose {
+		log.Println("building condensed tree")
+	}
+
+	length := c.length()
+	t := &condensedTree{
+		pointNode:   make([]int, length),
+		pointLambda: make([]float64, length),
+	}
+
+	components := newUnionFind(length)
+	size := make([]int, length)
+	node := make([]int, length)
+	// members of small components as linked lists
+	head := make([]int, length)
+	tail := make([]int, length)
+	next := make([]int, length)
+
+	for p := 0; p < length; p++ {
+		size[p] = 1
+		node[p] = -1
+		head[p], tail[p], next[p] = p, p, -1
+		t.pointNode[p] = -1
+		if c.mcs == 1 {
+			// a single point is a cluster that vanishes at its birth
+			node[p] = t.addNode(1, 0)
+			t.pointNode[p] = node[p]
+		}
+	}
+
+	fallOut := func(root, n int, lambda float64) {
+		for p := head[root]; p >= 0; p = next[p] {
+			t.pointNode[p] = n
+			t.pointLambda[p] = lambda
+		}
+	}
+
+	merge := func(e edge) {
+		a, b := components.find(e.p1), components.find(e.p2)
+		if a == b {
+			return
+		}
+
+		lambda := toLambda(e.dist)
+		merged := size[a] + size[b]
+		n := -1
+		switch {
+		case node[a] >= 0 && node[b] >= 0:
+			n = t.addNode(merged, e.dist)
+			t.link(n, node[a])
+			t.link(n, node[b])
+		case node[a] >= 0:
+			n = node[a]
+			fallOut(b, n, lambda)
+			t.nodes[n].size = merged
+		case node[b] >= 0:
+			n = node[b]
+			fallOut(a, n, lambda)
+			t.nodes[n].size = merged
+		case merged >= c.mcs:
+			n = t.addNode(merged, e.dist)
+			fallOut(a, n, lambda)
+			fallOut(b, n, lambda)
+		}
+
+		components.union(a, b)
+		root := components.find(a)
+		if n < 0 {
+			next[tail[a]] = head[b]
+			head[root], tail[root] = head[a], tail[b]
+		}
+		size[root] = merged
+		node[root] = n
+	}
+
+	for _, e := range sorted {
+		merge(e)
+	}
+
+	// a graph that is not connected is joined at an infinite distance
+	first := components.find(0)
+	for p := 1; p < length; p++ {
+		merge(edge{p1: first, p2: p, dist: math.Inf(1)})
+	}
+
+	if c.verbose {
+		log.Println("finished building condensed tree, Number of nodes: ", len(t.nodes))
+	}
+
+	return t
+}
+
+func (t *condensedTree) addNode(size int, dist float64) int {
+	t.nodes = append(t.nodes, condensedNode{parent: -1, size: size, dist: dist})
+	return len(t.nodes) - 1
+}
+
+func (t *condensedTree) link(parent, child int) {
+	t.nodes[parent].children = append(t.nodes[parent].children, child)
+	t.nodes[child].parent = parent
+}
+
+// birth returns the lambda at which node n splits off from its parent.
+func (t *condensedTree) birth(n int) float64 {
+	if t.nodes[n].parent < 0 {
+		return 0
+	}
+	return toLambda(t.nodes[t.nodes[n].parent].dist)
+}
+
+// stabilities returns the stability of every node: the sum of
+// lambda_p - lambda_birth over the points that fall out of the node and
+// of size * (lambda_split - lambda_birth) over its children.
+func (t *condensedTree) stabilities() []float64 {
+	stability := make([]float64, len(t.nodes))
+	for p, n := range t.pointNode {
+		if n >= 0 {
+			stability[n] += math.Max(0, t.pointLambda[p]-t.birth(n))
+		}
+	}
+
+	for n, node := range t.nodes {
+		birth := t.birth(n)
+		split := toLambda(node.dist)
+		for _, child := range node.children {
+			stability[n] += float64(t.nodes[child].size) * (split - birth)
+		}
+	}
+
+	return stability
+}
+
+// toLambda returns 1 / dist, a distance of zero
+// gives the largest finite lambda.
+func toLambda(dist float64) float64 {
+	if dist <= 0 {
+		return math.MaxFloat64
+	}
+	return 1 / dist
+}
+
+// buildCondensedClusters creates a cluster without points for every node.
+// The ids are the node indexes, the points are assigned by
+// `assignCondensedPoints` after the selection.
+func (c *Clustering) buildCondensedClusters() {
+	var clusters clusters
+	for i, node := range c.condensed.nodes {
+		newCluster := &cluster{
+			id:       i,
+			dist:     node.dist,
+			count:    node.size,
+			Outliers: make(Outliers, 0),
+			children: append([]int(nil), node.children...),
+		}
+
+		if node.parent >= 0 {
+			parent := node.parent
+			newCluster.parent = &parent
+		}
+
+		clusters = append(clusters, newCluster)
+	}
+
+	c.Clusters = clusters
+	c.NumberOfClusters = len(clusters) - 1
+}
+
+// condensedStabilityScores scores every cluster by its stability per point.
+func (c *Clustering) condensedStabilityScores() {
+	stability := c.condensed.stabilities()
+	for _, cluster := range c.Clusters {
+		cluster.score = stability[cluster.id] / float64(cluster.count)
+	}
+	c.Clusters.sumChildScores()
+}
+
+// assignCondensedPoints fills the points of the selected clusters and
+// returns the index of the cluster of every point, -1 for noise.
+// A point belongs to the nearest selected cluster among the node it
+// falls out of and its ancestors. Selected clusters that keep no points
+// because all of them belong to selected descendants are removed.
+func (c *Clustering) assignCondensedPoints() []int {
+	selected := make([]int, len(c.condensed.nodes))
+	for n := range selected {
+		selected[n] = -1
+	}
+	for i, cluster := range c.Clusters {
+		selected[cluster.id] = i
+	}
+
+	// parents have larger indexes than their children
+	for n := len(selected) - 1; n >= 0; n-- {
+		parent := c.condensed.nodes[n].parent
+		if selected[n] < 0 && parent >= 0 {
+			selected[n] = selected[parent]
+		}
+	}
+
+	labels := make([]int, len(c.condensed.pointNode))
+	for p, n := range c.condensed.pointNode {
+		labels[p] = -1
+		if n >= 0 && selected[n] >= 0 {
+			labels[p] = selected[n]
+			c.Clusters[labels[p]].Points = append(c.Clusters[labels[p]].Points, p)
+		}
+	}
+
+	index := make([]int, len(c.Clusters))
+	var clusters clusters
+	for i, cluster := range c.Clusters {
+		index[i] = len(clusters)
+		if len(cluster.Points) > 0 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	c.Clusters = clusters
+
+	for p, label := range labels {
+		if label >= 0 {
+			labels[p] = index[label]
+		}
+	}
+
+	return labels
+}
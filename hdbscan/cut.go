@@ -0,0 +1,144 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"log"
+	"math"
+)
+
+// CutAt will cut the minimum spanning tree of the mutual reachability
+// graph at a fixed epsilon distance. It returns for every data point the
+// index of its flat cluster, or -1 if the point is noise, and the noise
+// points. The result is equal to DBSCAN* with `minPts` set to the
+// minimum cluster size and a radius of epsilon.
+// Components with fewer points than the minimum cluster size
+// are treated as noise. The clustering itself is not modified.
+func (c *Clustering) CutAt(epsilon float64) ([]int, []int, error) {
+	if c.dendogram == nil && c.condensed == nil {
+		return nil, nil, ErrNotFitted
+	}
+
+	if epsilon <= 0 {
+		return nil, nil, ErrEpsilon
+	}
+
+	if c.verbose {
+		log.Println("cutting minimum spanning tree at epsilon: ", epsilon)
+	}
+
+	labels := make([]int, c.length())
+	count := c.cutEdges(c.minSpanningTree(), epsilon, labels)
+
+	var noise []int
+	for p, label := range labels {
+		if label < 0 {
+			noise = append(noise, p)
+		}
+	}
+
+	if c.verbose {
+		log.Println("finished cutting minimum spanning tree, Number of clusters: ", count, "Noise points: ", len(noise))
+	}
+
+	return labels, noise, nil
+}
+
+// minSpanningTree returns the minimum spanning tree of the mutual
+// reachability graph. A DataSource clustering keeps the tree of `Fit`.
+// The edges of `Fit` for other data connect every point to its nearest
+// earlier point or to its nearest point, which is no minimum spanning tree,
+// so the tree is built once with Prim's algorithm from the core distances.
+// It needs O(N²) distance computations but no matrix.
+func (c *Clustering) minSpanningTree() edges {
+	if c.source != nil {
+		return c.mst.edges
+	}
+
+	if c.cutTree != nil {
+		return c.cutTree
+	}
+
+	length := c.length()
+	inTree := make([]bool, length)
+	best := make(edges, length)
+	for i := range best {
+		best[i] = edge{p1: -1, p2: i, dist: math.Inf(1)}
+	}
+
+	tree := make(edges, 0, length-1)
+	current := 0
+	inTree[current] = true
+	for len(tree) < length-1 {
+		next := -1
+		for j := 0; j < length; j++ {
+			if inTree[j] {
+				continue
+			}
+			e := c.mutualReachabilityEdge(current, j)
+			if e.dist < best[j].dist {
+				best[j] = e
+			}
+			if next < 0 || best[j].dist < best[next].dist {
+				next = j
+			}
+		}
+
+		tree = append(tree, best[next])
+		inTree[next] = true
+		current = next
+	}
+
+	sortEdges(tree)
+	c.cutTree = tree
+	return tree
+}
+
+// cutEdges labels the components of the edges up to epsilon
+// that have at least `mcs` points and returns the number of clusters.
+func (c *Clustering) cutEdges(tree edges, epsilon float64, labels []int) int {
+	components := newUnionFind(len(labels))
+	for _, e := range tree {
+		if e.dist <= epsilon {
+			components.union(e.p1, e.p2)
+		}
+	}
+
+	size := make([]int, len(labels))
+	for p := range labels {
+		size[components.find(p)]++
+	}
+
+	label := make([]int, len(labels))
+	for p := range label {
+		label[p] = -1
+	}
+
+	var count int
+	for p := range labels {
+		labels[p] = -1
+		root := components.find(p)
+		if size[root] < c.mcs {
+			continue
+		}
+		if label[root] < 0 {
+			label[root] = count
+			count++
+		}
+		labels[p] = label[root]
+	}
+
+	return count
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"sort"
+	"testing"
+)
+
+// dbscanStar labels the points like DBSCAN* from all pairwise mutual
+// reachability distances, the core distance of a point is the distance
+// to its mcs-th nearest point including itself.
+func dbscanStar(data [][]float64, mcs int, epsilon float64) []int {
+	core := make([]float64, len(data))
+	for i := range data {
+		var dists []float64
+		for j := range data {
+			dists = append(dists, EuclideanDistance(data[i], data[j]))
+		}
+		sort.Float64s(dists)
+		core[i] = dists[mcs-1]
+	}
+
+	components := newUnionFind(len(data))
+	for i := range data {
+		for j := i + 1; j < len(data); j++ {
+			if max([]float64{core[i], core[j], EuclideanDistance(data[i], data[j])}) <= epsilon {
+				components.union(i, j)
+			}
+		}
+	}
+
+	size := make(map[int]int)
+	for p := range data {
+		size[components.find(p)]++
+	}
+	labels := make([]int, len(data))
+	for p := range data {
+		labels[p] = -1
+		if root := components.find(p); size[root] >= mcs {
+			labels[p] = root
+		}
+	}
+	return labels
+}
+
+// samePartition reports whether both labelings have the same noise
+// points and group the other points in the same way.
+func samePartition(a, b []int) bool {
+	forward, backward := make(map[int]int), make(map[int]int)
+	for p := range a {
+		if (a[p] < 0) != (b[p] < 0) {
+			return false
+		}
+		if a[p] < 0 {
+			continue
+		}
+		if l, ok := forward[a[p]]; ok && l != b[p] {
+			return false
+		}
+		if l, ok := backward[b[p]]; ok && l != a[p] {
+			return false
+		}
+		forward[a[p]], backward[b[p]] = b[p], a[p]
+	}
+	return true
+}
+
+func TestCutAtDBSCANStar(t *testing.T) {
+	data := blobs([][]float64{{0, 0}, {6, 6}, {14, 0}}, 25, 3)
+	data = append(data, []float64{30, 30}, []float64{-20, 10})
+
+	for _, mcs := range []int{1, 4} {
+		clusterings := make(map[string]*Clustering)
+		for _, mst := range []bool{true, false} {
+			c, err := NewClustering(data, mcs, t.TempDir()+"/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Fit(EuclideanDistance, mst); err != nil {
+				t.Fatal(err)
+			}
+			if mst {
+				clusterings["mst"] = c
+			} else {
+				clusterings["nearest"] = c
+			}
+		}
+		c, err := NewSourceClustering(MemorySource(data), mcs, t.TempDir()+"/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Fit(EuclideanDistance, true); err != nil {
+			t.Fatal(err)
+		}
+		clusterings["source"] = c
+
+		for _, epsilon := range []float64{0.5, 1, 2, 4, 10} {
+			want := dbscanStar(data, mcs, epsilon)
+			for name, c := range clusterings {
+				labels, noise, err := c.CutAt(epsilon)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !samePartition(labels, want) {
+					t.Errorf("%s mcs %d epsilon %v: labels %v, want %v", name, mcs, epsilon, labels, want)
+				}
+				for _, p := range noise {
+					if labels[p] != -1 {
+						t.Errorf("%s mcs %d epsilon %v: noise point %d has label %d", name, mcs, epsilon, p, labels[p])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCutAtErrors(t *testing.T) {
+	c, err := NewClustering(blobs([][]float64{{0, 0}}, 10, 4), 3, t.TempDir()+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.CutAt(1); err != ErrNotFitted {
+		t.Errorf("CutAt before Fit returned %v, want ErrNotFitted", err)
+	}
+	if err := c.Fit(EuclideanDistance, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.CutAt(0); err != ErrEpsilon {
+		t.Errorf("CutAt(0) returned %v, want ErrEpsilon", err)
+	}
+}
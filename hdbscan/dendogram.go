@@ -10,6 +10,8 @@ type link struct {
 	children        []*link
 	points          []int
 	descendantCount int
+	// distance of the edge that formed the link
+	dist float64
 }
 
 type node struct {
@@ -59,6 +61,7 @@ func (c *Clustering) buildDendogram(baseEdge edges) []*link {
 				id:       len(links),
 				children: []*link{p1TopLink, p2TopLink},
 				points:   points,
+				dist:     e.dist,
 			}
 
 			p1TopLink.parent = &newLink
@@ -78,6 +81,7 @@ func (c *Clustering) buildDendogram(baseEdge edges) []*link {
 				id:       len(links),
 				children: []*link{p1TopLink},
 				points:   points,
+				dist:     e.dist,
 			}
 
 			p1TopLink.parent = &newlink
@@ -95,6 +99,7 @@ func (c *Clustering) buildDendogram(baseEdge edges) []*link {
 				id:       len(links),
 				children: []*link{p2TopLink},
 				points:   points,
+				dist:     e.dist,
 			}
 
 			p2TopLink.parent = &newlink
@@ -105,6 +110,7 @@ func (c *Clustering) buildDendogram(baseEdge edges) []*link {
 			newLink := link{
 				id:     len(links),
 				points: []int{e.p1, e.p2},
+				dist:   e.dist,
 			}
 
 			links = append(links, &newLink)
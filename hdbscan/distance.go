@@ -23,6 +23,9 @@ import (
 // DistanceFunc ...
 type DistanceFunc func(x1, x2 []float64) float64
 
+// DistanceFunc32 is used for the rows of a float32 Matrix.
+type DistanceFunc32 func(x1, x2 []float32) float64
+
 // EuclideanDistance ...
 var EuclideanDistance = func(v1, v2 []float64) float64 {
 	acc := 0.0
@@ -44,3 +47,20 @@ var AngleVector = func(v1, v2 []float64) float64 {
 	// return math.Acos(theta)
 	// return math.Acos(clamp(theta, -1, 1))
 }
+
+// EuclideanDistance32 ...
+var EuclideanDistance32 = func(v1, v2 []float32) float64 {
+	acc := 0.0
+	for i, v := range v1 {
+		d := float64(v - v2[i])
+		acc += d * d
+	}
+	return math.Sqrt(acc)
+}
+
+// AngleVector32 ...
+var AngleVector32 = func(v1, v2 []float32) float64 {
+	vec1 := r3.Vector{X: float64(v1[0]), Y: float64(v1[1]), Z: float64(v1[2])}
+	vec2 := r3.Vector{X: float64(v2[0]), Y: float64(v2[1]), Z: float64(v2[2])}
+	return float64(vec1.Angle(vec2).Radians())
+}
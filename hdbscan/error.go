@@ -23,4 +23,18 @@ var (
 	ErrDataLen = errors.New("length of data is less than minimum cluster size")
 	// ErrRowLength ...
 	ErrRowLength = errors.New("row is incorrect length")
+	// ErrNotFitted ...
+	ErrNotFitted = errors.New("clustering has not been fitted")
+	// ErrEpsilon ...
+	ErrEpsilon = errors.New("epsilon must be positive")
+	// ErrSourceHeader ...
+	ErrSourceHeader = errors.New("invalid data source header")
+	// ErrSourceRange ...
+	ErrSourceRange = errors.New("rows are out of the data source range")
+	// ErrSourceRows ...
+	ErrSourceRows = errors.New("data source clustering does not keep its rows in memory")
+	// ErrMatrixShape ...
+	ErrMatrixShape = errors.New("matrix shape does not match the data")
+	// ErrDistance32 ...
+	ErrDistance32 = errors.New("float32 matrix requires a DistanceFunc32")
 )
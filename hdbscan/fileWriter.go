@@ -3,32 +3,27 @@ package hdbscan
 import (
 	"bufio"
 	"fmt"
-	"math/rand"
 	"os"
-	"time"
 
 	"github.com/edgeDetection/edgedetection"
 )
 
-func getcolors(k int) ([]edgedetection.Color, []string) {
-	time := time.Now().Nanosecond()
-	rand.Seed(int64(time))
-
-	colors := make([]edgedetection.Color, k)
-	colorname := make([]string, k)
-	for i := 0; i < k; i++ {
-
-		randnum := rand.Intn(len(edgedetection.Colorsrand)) // colorsrand[randnum]
-		color := edgedetection.Colorsrand[randnum]
-
-		colors[i], _ = edgedetection.IsColorName(color)
-		colorname[i] = color
+// getcolors returns k palette colors. With a seed or a random source
+// the colors are assigned to the clusters in an order drawn from it,
+// without one every cluster keeps the color of its index.
+func (c *Clustering) getcolors(k int) ([]edgedetection.Color, []string) {
+	colors, names := edgedetection.PaletteColors(k)
+	if c.random != nil {
+		c.random.Shuffle(len(colors), func(i, j int) {
+			colors[i], colors[j] = colors[j], colors[i]
+			names[i], names[j] = names[j], names[i]
+		})
 	}
-	return colors, colorname
+	return colors, names
 }
 
 func (c *Clustering) writeClusterToObj() {
-	colors, _ := getcolors(len(c.Clusters))
+	colors, _ := c.getcolors(len(c.Clusters))
 	for i, cl := range c.Clusters {
 		outputfile, _ := os.Create(c.directory + "cluster_" + fmt.Sprint(i) + "_.obj")
 		defer outputfile.Close()
@@ -36,9 +31,9 @@ func (c *Clustering) writeClusterToObj() {
 
 		for _, p := range cl.Points {
 
-			x := fmt.Sprintf("%f", c.data[p][0])
-			y := fmt.Sprintf("%f", c.data[p][1])
-			z := fmt.Sprintf("%f", c.data[p][2])
+			x := fmt.Sprintf("%f", c.point(p)[0])
+			y := fmt.Sprintf("%f", c.point(p)[1])
+			z := fmt.Sprintf("%f", c.point(p)[2])
 
 			c := colors[i]
 			R := fmt.Sprintf("%1.3f", c.R)
@@ -52,9 +47,9 @@ func (c *Clustering) writeClusterToObj() {
 		}
 		for _, p := range cl.Outliers {
 
-			x := fmt.Sprintf("%f", c.data[p.Index][0])
-			y := fmt.Sprintf("%f", c.data[p.Index][1])
-			z := fmt.Sprintf("%f", c.data[p.Index][2])
+			x := fmt.Sprintf("%f", c.point(p.Index)[0])
+			y := fmt.Sprintf("%f", c.point(p.Index)[1])
+			z := fmt.Sprintf("%f", c.point(p.Index)[2])
 
 			// c := colors[i]
 			R := fmt.Sprintf("%1.3f", 0.502)
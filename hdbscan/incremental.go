@@ -0,0 +1,367 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"log"
+	"sort"
+)
+
+// EventType describes how a cluster changed
+// between two incremental clustering results.
+type EventType int
+
+const (
+	// Birth is a cluster without a predecessor.
+	Birth EventType = iota
+	// Death is a cluster without a successor.
+	Death
+	// Merge is a cluster that has two or more predecessors.
+	Merge
+	// Split is a cluster that has two or more successors.
+	Split
+)
+
+func (e EventType) String() string {
+	switch e {
+	case Birth:
+		return "birth"
+	case Death:
+		return "death"
+	case Merge:
+		return "merge"
+	case Split:
+		return "split"
+	}
+	return "unknown"
+}
+
+// Event is emitted by `Insert` for every cluster
+// that did not simply continue from the previous result.
+// `From` holds indexes into the clusters before the insert,
+// `To` holds indexes into the clusters after the insert.
+type Event struct {
+	Type EventType
+	From []int
+	To   []int
+}
+
+// Insert will add new data points to a fitted clustering.
+// Only the core distances that are affected by the new points
+// are recomputed and the edges of `Fit` are repaired locally
+// instead of building the mutual reachability graph from scratch:
+// the minimum spanning tree if `Fit` built one, otherwise the
+// nearest neighbour edge of every point.
+// Afterwards the hierarchy is rebuilt and the clusters are selected
+// again with the options of the last `Select` call.
+// The returned events describe how the clusters changed.
+// A DataSource clustering does not keep its rows in memory
+// and returns ErrSourceRows.
+func (c *Clustering) Insert(data [][]float64) ([]Event, error) {
+	if c.source != nil {
+		return nil, ErrSourceRows
+	}
+
+	if c.dendogram == nil {
+		return nil, ErrNotFitted
+	}
+
+	for _, row := range data {
+		if len(row) != c.dim() {
+			return nil, ErrRowLength
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if c.verbose {
+		log.Println("inserting points: ", len(data))
+	}
+
+	previous := c.Clusters
+	oldLength := c.length()
+	c.appendData(data)
+
+	changed := c.updateCoreDistances(oldLength)
+	if c.minTree {
+		c.mst.edges = c.repairSpanningTree(oldLength, changed)
+	} else {
+		c.mst.edges = c.repairNearestEdges(changed)
+	}
+	c.sortTreeEdges()
+
+	c.dendogram = c.buildDendogram(c.mst.edges)
+	c.cutTree = nil
+	err := c.Select(c.selectOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	events := c.Clusters.events(previous, oldLength, c.mcs)
+
+	if c.verbose {
+		log.Println("finished inserting points, Number of events: ", len(events))
+	}
+
+	return events, nil
+}
+
+// appendData adds data points to the data or the matrix of the clustering.
+// The rows are copied into a new slice, so the slice of the caller
+// is never written.
+func (c *Clustering) appendData(data [][]float64) {
+	if c.matrix == nil {
+		rows := make([][]float64, len(c.data), len(c.data)+len(data))
+		copy(rows, c.data)
+		c.data = append(rows, data...)
+		return
+	}
+
+	c.matrix.appendRows(data)
+	if c.data != nil {
+		c.data = c.matrix.rowViews()
+	}
+}
+
+// updateCoreDistances grows the lambda matrix and the core distances
+// for all points from index `start` on. Core distances of existing points
+// can only shrink, they are recomputed only if a new point is closer than
+// the current core distance.
+// It returns the indexes of all new points and all points with
+// a changed core distance.
+func (c *Clustering) updateCoreDistances(start int) []int {
+	length := c.length()
+	affected := make([]bool, start)
+
+	for i := 0; i < start; i++ {
+		for j := start; j < length; j++ {
+			dist := c.distance(i, j)
+			if c.lambda != nil {
+				c.lambda[i] = append(c.lambda[i], 1/dist)
+			}
+			if dist < c.coreDistances[i] {
+				affected[i] = true
+			}
+		}
+	}
+
+	for i := start; i < length; i++ {
+		if c.lambda != nil {
+			row := make([]float64, length)
+			for j := 0; j < length; j++ {
+				row[j] = 1 / c.distance(i, j)
+			}
+			c.lambda = append(c.lambda, row)
+		}
+		c.coreDistances = append(c.coreDistances, 0)
+	}
+
+	var changed []int
+	for i := 0; i < length; i++ {
+		if i < start && !affected[i] {
+			continue
+		}
+
+		c.wg.Add(1)
+		c.semaphore <- true
+		go func(i int) {
+			pointDistances := make([]float64, length)
+			for j := 0; j < length; j++ {
+				pointDistances[j] = c.distance(i, j)
+			}
+			sort.Float64s(pointDistances)
+			c.coreDistances[i] = pointDistances[c.mcs-1]
+			<-c.semaphore
+			c.wg.Done()
+		}(i)
+		changed = append(changed, i)
+	}
+	c.wg.Wait()
+
+	return changed
+}
+
+// repairSpanningTree repairs the tree of addRowToMinSpanningTree, which
+// connects every vertex to the nearest vertex that was added before it.
+// The nearest earlier vertex of an unchanged point is either its old one
+// or a changed point, only changed points are compared to all earlier
+// vertices. New points are added as vertices in index order.
+func (c *Clustering) repairSpanningTree(oldLength int, changed []int) edges {
+	length := c.length()
+	for i := oldLength; i < length; i++ {
+		c.mst.addVertice(i)
+	}
+
+	position := make([]int, length)
+	for k, v := range c.mst.vertices {
+		position[v] = k
+	}
+	previous := make([]int, length)
+	for _, e := range c.mst.edges {
+		previous[e.p2] = e.p1
+	}
+	isChanged := make([]bool, length)
+	for _, i := range changed {
+		isChanged[i] = true
+	}
+
+	tree := make(edges, len(c.mst.vertices)-1)
+	for k := 1; k < len(c.mst.vertices); k++ {
+		c.wg.Add(1)
+		c.semaphore <- true
+		go func(k int) {
+			i := c.mst.vertices[k]
+			n := nearestPoint{index: -1}
+			if isChanged[i] {
+				for _, j := range c.mst.vertices[:k] {
+					n.add(j, position[j], c.mutualReachabilityEdge(i, j).dist)
+				}
+			} else {
+				j := previous[i]
+				n.add(j, position[j], c.mutualReachabilityEdge(i, j).dist)
+				for _, j := range changed {
+					if position[j] < k {
+						n.add(j, position[j], c.mutualReachabilityEdge(i, j).dist)
+					}
+				}
+			}
+			tree[k-1] = edge{p1: n.index, p2: i, dist: n.dist}
+			<-c.semaphore
+			c.wg.Done()
+		}(k)
+	}
+	c.wg.Wait()
+
+	return tree
+}
+
+// repairNearestEdges repairs the edges of a clustering without minimum
+// spanning tree, which connect every point to the point with the smallest
+// mutual reachability distance. As for the tree, the nearest point of an
+// unchanged point is either its old one or a changed point.
+func (c *Clustering) repairNearestEdges(changed []int) edges {
+	length := c.length()
+	previous := make([]int, length)
+	for _, e := range c.mst.edges {
+		previous[e.p1] = e.p2
+	}
+	isChanged := make([]bool, length)
+	for _, i := range changed {
+		isChanged[i] = true
+	}
+
+	nearest := make(edges, length)
+	for i := 0; i < length; i++ {
+		c.wg.Add(1)
+		c.semaphore <- true
+		go func(i int) {
+			n := nearestPoint{index: -1}
+			if isChanged[i] {
+				for j := 0; j < length; j++ {
+					n.add(j, j, c.mutualReachabilityEdge(i, j).dist)
+				}
+			} else {
+				n.add(previous[i], previous[i], c.mutualReachabilityEdge(i, previous[i]).dist)
+				for _, j := range changed {
+					n.add(j, j, c.mutualReachabilityEdge(i, j).dist)
+				}
+			}
+			nearest[i] = edge{p1: i, p2: n.index, dist: n.dist}
+			<-c.semaphore
+			c.wg.Done()
+		}(i)
+	}
+	c.wg.Wait()
+
+	return nearest
+}
+
+// nearestPoint keeps the point with the smallest distance,
+// ties are broken by the smaller rank like the first minimum of a row.
+type nearestPoint struct {
+	index int
+	rank  int
+	dist  float64
+}
+
+func (n *nearestPoint) add(index, rank int, dist float64) {
+	if n.index < 0 || dist < n.dist || dist == n.dist && rank < n.rank {
+		n.index, n.rank, n.dist = index, rank, dist
+	}
+}
+
+func (c *Clustering) mutualReachabilityEdge(p1, p2 int) edge {
+	return edge{
+		p1:   p1,
+		p2:   p2,
+		dist: max([]float64{c.coreDistances[p1], c.coreDistances[p2], c.distance(p1, p2)}),
+	}
+}
+
+// events compares the clusters with the clusters of a previous result.
+// Only the first `length` points existed before and a cluster is
+// considered a successor of another if they share at least `mcs` points.
+func (c clusters) events(previous clusters, length, mcs int) []Event {
+	membership := make([]int, length)
+	for i := range membership {
+		membership[i] = -1
+	}
+	for i, cluster := range previous {
+		for _, p := range cluster.Points {
+			if p < length {
+				membership[p] = i
+			}
+		}
+	}
+
+	sources := make([][]int, len(c))
+	targets := make([][]int, len(previous))
+	for j, cluster := range c {
+		shared := make(map[int]int)
+		for _, p := range cluster.Points {
+			if p < length && membership[p] >= 0 {
+				shared[membership[p]]++
+			}
+		}
+		for i := range previous {
+			if shared[i] >= mcs {
+				sources[j] = append(sources[j], i)
+				targets[i] = append(targets[i], j)
+			}
+		}
+	}
+
+	var events []Event
+	for i, t := range targets {
+		switch {
+		case len(t) == 0:
+			events = append(events, Event{Type: Death, From: []int{i}})
+		case len(t) > 1:
+			events = append(events, Event{Type: Split, From: []int{i}, To: t})
+		}
+	}
+	for j, s := range sources {
+		switch {
+		case len(s) == 0:
+			events = append(events, Event{Type: Birth, To: []int{j}})
+		case len(s) > 1:
+			events = append(events, Event{Type: Merge, From: s, To: []int{j}})
+		}
+	}
+
+	return events
+}
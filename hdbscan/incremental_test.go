@@ -0,0 +1,96 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// blobs returns n points around every center in a shuffled order.
+func blobs(centers [][]float64, n int, seed int64) [][]float64 {
+	r := rand.New(rand.NewSource(seed))
+	var data [][]float64
+	for _, center := range centers {
+		for i := 0; i < n; i++ {
+			point := make([]float64, len(center))
+			for j, v := range center {
+				point[j] = v + r.NormFloat64()
+			}
+			data = append(data, point)
+		}
+	}
+	r.Shuffle(len(data), func(i, j int) { data[i], data[j] = data[j], data[i] })
+	return data
+}
+
+func TestInsertEqualsFit(t *testing.T) {
+	data := blobs([][]float64{{0, 0}, {10, 10}, {20, 0}}, 40, 1)
+	opts := SelectOptions{Score: VarianceScore, OutlierDetection: true}
+
+	for _, mst := range []bool{true, false} {
+		full, err := NewClustering(data, 5, t.TempDir()+"/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		full.Deterministic()
+		if err := full.Fit(EuclideanDistance, mst); err != nil {
+			t.Fatal(err)
+		}
+		if err := full.Select(opts); err != nil {
+			t.Fatal(err)
+		}
+
+		incremental, err := NewClustering(data[:80], 5, t.TempDir()+"/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		incremental.Deterministic()
+		if err := incremental.Fit(EuclideanDistance, mst); err != nil {
+			t.Fatal(err)
+		}
+		if err := incremental.Select(opts); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := incremental.Insert(data[80:100]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := incremental.Insert(data[100:]); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(incremental.mst.edges, full.mst.edges) {
+			t.Errorf("mst %v: edges after Insert differ from Fit", mst)
+		}
+		if !reflect.DeepEqual(incremental.Labels(), full.Labels()) {
+			t.Errorf("mst %v: labels after Insert %v, want %v", mst, incremental.Labels(), full.Labels())
+		}
+	}
+}
+
+func TestInsertDataSource(t *testing.T) {
+	data := blobs([][]float64{{0, 0}, {10, 10}}, 20, 2)
+	c, err := NewSourceClustering(MemorySource(data), 5, t.TempDir()+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Fit(EuclideanDistance, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Insert(data[:2]); err != ErrSourceRows {
+		t.Errorf("Insert on a DataSource clustering returned %v, want ErrSourceRows", err)
+	}
+}
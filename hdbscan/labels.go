@@ -0,0 +1,69 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+// Len returns the number of data points of the clustering.
+func (c *Clustering) Len() int {
+	return c.length()
+}
+
+// Labels returns for every data point the index of its cluster
+// in `Clusters`, or -1 if the point is noise.
+// Outliers are labeled with their cluster only if they
+// have been added to it by the voronoi option.
+func (c *Clustering) Labels() []int {
+	labels := make([]int, c.length())
+	for i := range labels {
+		labels[i] = -1
+	}
+
+	for i, cluster := range c.Clusters {
+		for _, p := range cluster.Points {
+			labels[p] = i
+		}
+	}
+
+	return labels
+}
+
+// OutlierScores returns for every data point its normalized
+// outlier distance, or 0 if the point is not an outlier.
+func (c *Clustering) OutlierScores() []float64 {
+	scores := make([]float64, c.length())
+	for _, cluster := range c.Clusters {
+		for _, o := range cluster.Outliers {
+			scores[o.Index] = o.NormalizedDistance
+		}
+	}
+
+	return scores
+}
+
+// Probabilities returns for every data point the probability
+// that it belongs to its cluster. Points selected by the density
+// clustering have a probability of 1, outliers added by the voronoi
+// option 1 minus their outlier score and noise points 0.
+func (c *Clustering) Probabilities() []float64 {
+	labels := c.Labels()
+	scores := c.OutlierScores()
+	probabilities := make([]float64, len(labels))
+	for i, label := range labels {
+		if label >= 0 {
+			probabilities[i] = 1 - scores[i]
+		}
+	}
+
+	return probabilities
+}
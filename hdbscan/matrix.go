@@ -0,0 +1,210 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+// Matrix holds data points in a single flat slice
+// of either float64 or float32 values.
+// Row i starts at index i*stride, which allows
+// padded rows or interleaved attributes.
+type Matrix struct {
+	rows   int
+	cols   int
+	stride int
+	f64    []float64
+	f32    []float32
+}
+
+// NewMatrix creates a float64 matrix of `rows` data points
+// with `cols` values each. A stride of 0 is equal to `cols`.
+func NewMatrix(data []float64, rows, cols, stride int) (*Matrix, error) {
+	stride, err := matrixStride(len(data), rows, cols, stride)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Matrix{rows: rows, cols: cols, stride: stride, f64: data}, nil
+}
+
+// NewMatrix32 creates a float32 matrix of `rows` data points
+// with `cols` values each. A stride of 0 is equal to `cols`.
+// Float32 data needs half the memory, distances are computed
+// with the DistanceFunc32 set by `Distance32`.
+func NewMatrix32(data []float32, rows, cols, stride int) (*Matrix, error) {
+	stride, err := matrixStride(len(data), rows, cols, stride)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Matrix{rows: rows, cols: cols, stride: stride, f32: data}, nil
+}
+
+func matrixStride(length, rows, cols, stride int) (int, error) {
+	if stride == 0 {
+		stride = cols
+	}
+
+	if rows < 1 || cols < 1 || stride < cols {
+		return 0, ErrMatrixShape
+	}
+
+	if (rows-1)*stride+cols > length {
+		return 0, ErrMatrixShape
+	}
+
+	return stride, nil
+}
+
+// Len returns the number of data points.
+func (m *Matrix) Len() int {
+	return m.rows
+}
+
+// Dim returns the length of every data point.
+func (m *Matrix) Dim() int {
+	return m.cols
+}
+
+// IsFloat32 returns true if the matrix stores float32 values.
+func (m *Matrix) IsFloat32() bool {
+	return m.f32 != nil
+}
+
+// Row returns data point i as float64 values.
+// For a float64 matrix the row shares the memory of the matrix,
+// for a float32 matrix it is a converted copy.
+func (m *Matrix) Row(i int) []float64 {
+	if m.f32 == nil {
+		return m.rowInto(i, nil)
+	}
+	return m.rowInto(i, make([]float64, m.cols))
+}
+
+// rowInto is Row without allocation, a float32 row
+// is converted into buf, which holds `cols` values.
+func (m *Matrix) rowInto(i int, buf []float64) []float64 {
+	start := i * m.stride
+	if m.f32 == nil {
+		return m.f64[start : start+m.cols : start+m.cols]
+	}
+
+	for j, v := range m.f32[start : start+m.cols] {
+		buf[j] = float64(v)
+	}
+	return buf
+}
+
+// Row32 returns data point i of a float32 matrix.
+// The row shares the memory of the matrix.
+func (m *Matrix) Row32(i int) []float32 {
+	start := i * m.stride
+	return m.f32[start : start+m.cols : start+m.cols]
+}
+
+// ReadRows ...
+func (m *Matrix) ReadRows(start int, dst [][]float64) error {
+	if start < 0 || start+len(dst) > m.rows {
+		return ErrSourceRange
+	}
+
+	for i := range dst {
+		offset := (start + i) * m.stride
+		if m.f32 == nil {
+			copy(dst[i], m.f64[offset:offset+m.cols])
+			continue
+		}
+		for j, v := range m.f32[offset : offset+m.cols] {
+			dst[i][j] = float64(v)
+		}
+	}
+	return nil
+}
+
+// rowViews returns all rows of a float64 matrix
+// as slices sharing the memory of the matrix.
+func (m *Matrix) rowViews() [][]float64 {
+	views := make([][]float64, m.rows)
+	for i := range views {
+		views[i] = m.Row(i)
+	}
+	return views
+}
+
+// addRow adds data point i to sum.
+func (m *Matrix) addRow(i int, sum []float64) {
+	start := i * m.stride
+	if m.f32 == nil {
+		for j, v := range m.f64[start : start+m.cols] {
+			sum[j] += v
+		}
+		return
+	}
+	for j, v := range m.f32[start : start+m.cols] {
+		sum[j] += float64(v)
+	}
+}
+
+// gather returns the data points with the given indexes
+// as one flat float64 slice.
+func (m *Matrix) gather(points []int) []float64 {
+	flat := make([]float64, 0, len(points)*m.cols)
+	for _, p := range points {
+		start := p * m.stride
+		if m.f32 == nil {
+			flat = append(flat, m.f64[start:start+m.cols]...)
+			continue
+		}
+		for _, v := range m.f32[start : start+m.cols] {
+			flat = append(flat, float64(v))
+		}
+	}
+	return flat
+}
+
+// appendRows adds data points to the matrix.
+// The matrix is first repacked without padding into a new slice,
+// so the slice of the caller is never written.
+func (m *Matrix) appendRows(data [][]float64) {
+	m.repack(len(data))
+
+	for _, row := range data {
+		if m.f32 == nil {
+			m.f64 = append(m.f64, row...)
+		} else {
+			for _, v := range row {
+				m.f32 = append(m.f32, float32(v))
+			}
+		}
+		m.rows++
+	}
+}
+
+// repack copies the rows into a new slice without padding
+// with capacity for `extra` more rows.
+func (m *Matrix) repack(extra int) {
+	if m.f32 == nil {
+		f64 := make([]float64, 0, (m.rows+extra)*m.cols)
+		for i := 0; i < m.rows; i++ {
+			f64 = append(f64, m.Row(i)...)
+		}
+		m.f64 = f64
+	} else {
+		f32 := make([]float32, 0, (m.rows+extra)*m.cols)
+		for i := 0; i < m.rows; i++ {
+			f32 = append(f32, m.Row32(i)...)
+		}
+		m.f32 = f32
+	}
+	m.stride = m.cols
+}
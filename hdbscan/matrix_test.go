@@ -0,0 +1,84 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"testing"
+)
+
+// benchmarkData returns the same points as rows
+// and as flat float64 and float32 slices.
+func benchmarkData() ([][]float64, []float64, []float32) {
+	rows := blobs([][]float64{{0, 0, 0}, {10, 10, 10}, {20, 0, 5}}, 200, 1)
+	var f64 []float64
+	var f32 []float32
+	for _, row := range rows {
+		for _, v := range row {
+			f64 = append(f64, v)
+			f32 = append(f32, float32(v))
+		}
+	}
+	return rows, f64, f32
+}
+
+func benchmarkClustering(b *testing.B, newClustering func(directory string) (*Clustering, error)) {
+	b.ReportAllocs()
+	opts := SelectOptions{Score: VarianceScore, OutlierDetection: true, NearestNeighbor: true}
+	for i := 0; i < b.N; i++ {
+		c, err := newClustering(b.TempDir() + "/")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := c.Fit(EuclideanDistance, true); err != nil {
+			b.Fatal(err)
+		}
+		if err := c.Select(opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClusteringSlices(b *testing.B) {
+	rows, _, _ := benchmarkData()
+	benchmarkClustering(b, func(directory string) (*Clustering, error) {
+		return NewClustering(rows, 10, directory)
+	})
+}
+
+func BenchmarkClusteringMatrix64(b *testing.B) {
+	rows, f64, _ := benchmarkData()
+	benchmarkClustering(b, func(directory string) (*Clustering, error) {
+		m, err := NewMatrix(f64, len(rows), 3, 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewMatrixClustering(m, 10, directory)
+	})
+}
+
+func BenchmarkClusteringMatrix32(b *testing.B) {
+	rows, _, f32 := benchmarkData()
+	benchmarkClustering(b, func(directory string) (*Clustering, error) {
+		m, err := NewMatrix32(f32, len(rows), 3, 0)
+		if err != nil {
+			return nil, err
+		}
+		c, err := NewMatrixClustering(m, 10, directory)
+		if err != nil {
+			return nil, err
+		}
+		return c.Distance32(EuclideanDistance32), nil
+	})
+}
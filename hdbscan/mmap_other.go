@@ -0,0 +1,49 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package hdbscan
+
+import "os"
+
+// MmapSource is a DataSource for a file in the binary source format.
+// Memory mapping is not supported on this platform,
+// so the rows are read in chunks instead.
+type MmapSource struct {
+	*ReaderSource
+	f *os.File
+}
+
+// OpenMmapSource opens a file in the binary source format.
+func OpenMmapSource(path string) (*MmapSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := NewReaderSource(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &MmapSource{ReaderSource: s, f: f}, nil
+}
+
+// Close closes the file.
+func (m *MmapSource) Close() error {
+	return m.f.Close()
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package hdbscan
+
+import (
+	"os"
+	"syscall"
+)
+
+// MmapSource is a DataSource backed by a memory mapped
+// file in the binary source format.
+type MmapSource struct {
+	data []byte
+	rows int
+	dim  int
+}
+
+// OpenMmapSource maps a file in the binary source format into memory.
+// The operating system pages the rows in and out as they are read,
+// so the file can be larger than the available memory.
+func OpenMmapSource(path string) (*MmapSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < sourceHeaderSize {
+		return nil, ErrSourceHeader
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, dim, err := parseSourceHeader(data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+
+	if int64(sourceHeaderSize+rows*dim*8) > info.Size() {
+		syscall.Munmap(data)
+		return nil, ErrSourceHeader
+	}
+
+	return &MmapSource{data: data, rows: rows, dim: dim}, nil
+}
+
+// Len ...
+func (m *MmapSource) Len() int {
+	return m.rows
+}
+
+// Dim ...
+func (m *MmapSource) Dim() int {
+	return m.dim
+}
+
+// ReadRows ...
+func (m *MmapSource) ReadRows(start int, dst [][]float64) error {
+	if start < 0 || start+len(dst) > m.rows {
+		return ErrSourceRange
+	}
+
+	offset := sourceHeaderSize + start*m.dim*8
+	decodeRows(m.data[offset:], dst, m.dim)
+	return nil
+}
+
+// Close unmaps the file.
+func (m *MmapSource) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	err := syscall.Munmap(m.data)
+	m.data = nil
+	return err
+}
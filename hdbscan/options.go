@@ -14,6 +14,8 @@
 
 package hdbscan
 
+import "math/rand"
+
 // OutlierDetection will track all unassigned
 // points as outliers of their nearest cluster.
 // It provides a `NormalizedDistance` value for
@@ -63,3 +65,82 @@ func (c *Clustering) OutlierClustering() *Clustering {
 	c.oc = true
 	return c
 }
+
+// Epsilon sets the distance threshold that `Run` passes to `Select`.
+// Clusters that split off below it are merged back into their
+// nearest ancestor that was born at or above it. Zero disables it.
+func (c *Clustering) Epsilon(epsilon float64) *Clustering {
+	if epsilon >= 0 {
+		c.epsilon = epsilon
+	}
+	return c
+}
+
+// BlockSize sets the number of rows that are read at once
+// from a DataSource by `NewSourceClustering`.
+func (c *Clustering) BlockSize(n int) *Clustering {
+	if n > 0 {
+		c.blockSize = n
+	}
+	return c
+}
+
+// Distance32 sets the distance function that is used
+// for the rows of a float32 Matrix.
+func (c *Clustering) Distance32(distanceFunc DistanceFunc32) *Clustering {
+	c.distanceFunc32 = distanceFunc
+	return c
+}
+
+// Workers sets the number of goroutines that are used
+// for the distance computations. The default is the number of CPUs.
+func (c *Clustering) Workers(n int) *Clustering {
+	if n > 0 {
+		c.semaphore = make(chan bool, n)
+	}
+	return c
+}
+
+// Deterministic will make the clustering produce identical
+// results for identical input, independent of the number of workers
+// and the scheduling of the goroutines. The edges of the minimum
+// spanning tree are added in point order and ties between edges of
+// equal distance are broken by the point indexes.
+func (c *Clustering) Deterministic() *Clustering {
+	c.deterministic = true
+	return c
+}
+
+// Subsample will only use the first n data points in the clustering.
+// The remaining data points can be added with `Assign`.
+func (c *Clustering) Subsample(n int) *Clustering {
+	if n > 0 {
+		c.subSample = true
+		c.sampleBound = n
+	}
+	return c
+}
+
+// RandomSample will only use n data points in the clustering that are
+// drawn with the random generator set by `Seed` or `RandSource`.
+// `Sample` returns their indexes in the data.
+func (c *Clustering) RandomSample(n int) *Clustering {
+	if n > 0 {
+		c.randomSample = true
+		c.sampleBound = n
+	}
+	return c
+}
+
+// Seed makes all random decisions of the clustering reproducible,
+// e.g. the colors of the written clusters and the sampling.
+func (c *Clustering) Seed(seed int64) *Clustering {
+	c.random = rand.New(rand.NewSource(seed))
+	return c
+}
+
+// RandSource sets the source of all random decisions of the clustering.
+func (c *Clustering) RandSource(src rand.Source) *Clustering {
+	c.random = rand.New(src)
+	return c
+}
@@ -24,7 +24,9 @@ func (c *Clustering) outliersAndVoronoi() {
 		}
 	}
 
-	for i, v := range c.data {
+	buf := make([]float64, c.dim())
+	for i := 0; i < c.length(); i++ {
+		v := c.pointInto(i, buf)
 		var exists bool
 		for _, cluster := range c.Clusters {
 			for _, point := range cluster.Points {
@@ -43,20 +45,20 @@ func (c *Clustering) outliersAndVoronoi() {
 			// calculate nearest cluster
 			minDistance := math.MaxFloat64
 			var nearestClusterIndex int
-			for i, cluster := range c.Clusters {
+			for k, cluster := range c.Clusters {
 				if c.nn {
 					for _, p := range cluster.Points {
-						distance := c.distanceFunc(c.data[p], v)
+						distance := c.distance(p, i)
 						if distance < minDistance {
 							minDistance = distance
-							nearestClusterIndex = i
+							nearestClusterIndex = k
 						}
 					}
 				} else {
 					distance := c.distanceFunc(cluster.Centroid, v)
 					if distance < minDistance {
 						minDistance = distance
-						nearestClusterIndex = i
+						nearestClusterIndex = k
 					}
 				}
 			}
@@ -79,13 +81,7 @@ func (c *Clustering) outliersAndVoronoi() {
 	// normalize outlier distances
 	if c.od {
 		c.distanceDistributions()
-
-		for _, cluster := range c.Clusters {
-			for j, outlier := range cluster.Outliers {
-				outlier.NormalizedDistance = isNum(cluster.distanceDistribution.CDF(outlier.NormalizedDistance))
-				cluster.Outliers[j] = outlier
-			}
-		}
+		c.Clusters.normalizeOutliers()
 	}
 
 	if c.verbose {
@@ -99,6 +95,17 @@ func (c *Clustering) outliersAndVoronoi() {
 	}
 }
 
+// normalizeOutliers replaces the distance of every outlier by
+// its probability in the distance distribution of its cluster.
+func (c clusters) normalizeOutliers() {
+	for _, cluster := range c {
+		for j, outlier := range cluster.Outliers {
+			outlier.NormalizedDistance = isNum(cluster.distanceDistribution.CDF(outlier.NormalizedDistance))
+			cluster.Outliers[j] = outlier
+		}
+	}
+}
+
 func (c *Clustering) outlierClustering() {
 	if !c.oc {
 		return
@@ -61,23 +61,20 @@ func (c *Clustering) getCoords(e edge) plotter.XYs {
 	pts := make(plotter.XYs, 2)
 	p1 := e.p1
 	p2 := e.p2
-	for i, p := range c.data {
-		if i == p1 {
-			pts[0].X = p[0]
-			pts[0].Y = p[1]
-		}
-
-		if i == p2 {
-			pts[1].X = p[0]
-			pts[1].Y = p[1]
-		}
-	}
+	pts[0].X = c.point(p1)[0]
+	pts[0].Y = c.point(p1)[1]
+	pts[1].X = c.point(p2)[0]
+	pts[1].Y = c.point(p2)[1]
 	return pts
 }
 
 func (c *Clustering) findAxisLim() (float64, float64, float64, float64) {
-	xSorted := mergeSort(c.data, 0)
-	ySorted := mergeSort(c.data, 1)
+	data := make([][]float64, c.length())
+	for i := range data {
+		data[i] = c.point(i)
+	}
+	xSorted := mergeSort(data, 0)
+	ySorted := mergeSort(data, 1)
 
 	return xSorted[0][0], ySorted[0][1], xSorted[len(xSorted)-1][0], ySorted[len(xSorted)-1][1]
 }
@@ -29,62 +29,57 @@ func (c *Clustering) mutualReachabilityGraph() edges {
 		log.Println("starting mutual reachability")
 	}
 
-	lambda := make([][]float64, len(c.data))
-	// core-distances
-	length := len(c.data)
+	// core-distances, the lambda matrix is only kept for data
+	// that is not stored in a Matrix
+	length := c.length()
+	var lambda [][]float64
+	if c.matrix == nil {
+		lambda = make([][]float64, length)
+	}
 	coreDistances := make([]float64, length, length)
-	for i, p1 := range c.data {
-		lambda[i] = make([]float64, len(c.data))
+	for i := 0; i < length; i++ {
+		if lambda != nil {
+			lambda[i] = make([]float64, length)
+		}
 		c.wg.Add(1)
 		c.semaphore <- true
-		go func(i int, p1 []float64) {
-			pointDistances := []float64{}
-			for ii, p2 := range c.data {
-				dist := c.distanceFunc(p1, p2)
+		go func(i int) {
+			pointDistances := make([]float64, 0, length)
+			for ii := 0; ii < length; ii++ {
+				dist := c.distance(i, ii)
 				pointDistances = append(pointDistances, dist)
 				// Transform in lambda
-				lambda[i][ii] = (1 / dist)
+				if lambda != nil {
+					lambda[i][ii] = (1 / dist)
+				}
 			}
 			sort.Float64s(pointDistances)
 			coreDistances[i] = pointDistances[c.mcs-1]
 			<-c.semaphore
 			c.wg.Done()
-		}(i, p1)
+		}(i)
 	}
 	c.lambda = lambda
 	c.wg.Wait()
+	c.coreDistances = coreDistances
 
 	// mutual-reachability distances
-	for i := 0; i < length; i++ {
-		c.wg.Add(1)
-		c.semaphore <- true
-		go func(i int) {
-			mutualReachabilityDistances := make([]float64, length, length)
-			// the mutual reachability distance is the maximum of:
-			// point_1's core-distance, point_2's core-distance, or the distance between point_1 and point_2
-			// max{dcore(xp),dcore(xq),d(xp,xq)}
-			for j := 0; j < length; j++ {
-				mutualReachabilityDistances[j] = max([]float64{coreDistances[i], coreDistances[j], c.distanceFunc(c.data[i], c.data[j])})
-			}
-
-			if c.minTree {
-				c.addRowToMinSpanningTree(i, mutualReachabilityDistances)
-			} else {
-				minIndex, minValue := min(mutualReachabilityDistances)
-				e := edge{
-					p1:   i,
-					p2:   minIndex,
-					dist: minValue,
-				}
-
-				// just use tree for edge storage
-				c.mst.addEdge(e)
-			}
-			<-c.semaphore
-			c.wg.Done()
-		}(i)
+	if c.deterministic {
+		c.orderedMutualReachability(length)
+	} else {
+		for i := 0; i < length; i++ {
+			c.wg.Add(1)
+			c.semaphore <- true
+			go func(i int) {
+				mutualReachabilityDistances := make([]float64, length, length)
+				c.mutualReachabilityRow(i, mutualReachabilityDistances)
+				c.addMutualReachabilityRow(i, mutualReachabilityDistances)
+				<-c.semaphore
+				c.wg.Done()
+			}(i)
+		}
+		c.wg.Wait()
 	}
-	c.wg.Wait()
 
 	outputfile, _ := os.Create(c.directory + "debug1.txt")
 	defer outputfile.Close()
@@ -104,7 +99,7 @@ func (c *Clustering) mutualReachabilityGraph() edges {
 	// Very important to invoke after writing a large number of lines
 	writer.Flush()
 
-	sort.Sort(c.mst.edges)
+	c.sortTreeEdges()
 
 	outputfile, _ = os.Create(c.directory + "debug2.txt")
 	defer outputfile.Close()
@@ -130,3 +125,70 @@ func (c *Clustering) mutualReachabilityGraph() edges {
 
 	return c.mst.edges
 }
+
+// orderedMutualReachability computes the mutual reachability rows in
+// batches of one row per worker and adds them to the tree in row order,
+// so the tree does not depend on the scheduling of the goroutines.
+func (c *Clustering) orderedMutualReachability(length int) {
+	workers := cap(c.semaphore)
+	rows := make([][]float64, workers)
+	for i := range rows {
+		rows[i] = make([]float64, length, length)
+	}
+
+	for start := 0; start < length; start += workers {
+		end := minInt(start+workers, length)
+		for i := start; i < end; i++ {
+			c.wg.Add(1)
+			c.semaphore <- true
+			go func(i int) {
+				c.mutualReachabilityRow(i, rows[i-start])
+				<-c.semaphore
+				c.wg.Done()
+			}(i)
+		}
+		c.wg.Wait()
+
+		for i := start; i < end; i++ {
+			c.addMutualReachabilityRow(i, rows[i-start])
+		}
+	}
+}
+
+// the mutual reachability distance is the maximum of:
+// point_1's core-distance, point_2's core-distance, or the distance between point_1 and point_2
+// max{dcore(xp),dcore(xq),d(xp,xq)}
+func (c *Clustering) mutualReachabilityRow(i int, mutualReachabilityDistances []float64) {
+	for j := range mutualReachabilityDistances {
+		mutualReachabilityDistances[j] = max([]float64{c.coreDistances[i], c.coreDistances[j], c.distance(i, j)})
+	}
+}
+
+func (c *Clustering) addMutualReachabilityRow(i int, mutualReachabilityDistances []float64) {
+	if c.minTree {
+		c.addRowToMinSpanningTree(i, mutualReachabilityDistances)
+		return
+	}
+
+	minIndex, minValue := min(mutualReachabilityDistances)
+	e := edge{
+		p1:   i,
+		p2:   minIndex,
+		dist: minValue,
+	}
+
+	// just use tree for edge storage
+	c.mst.Lock()
+	c.mst.addEdge(e)
+	c.mst.Unlock()
+}
+
+// sortTreeEdges sorts the edges of the tree, by distance only or
+// with ties broken by the point indexes if the clustering is deterministic.
+func (c *Clustering) sortTreeEdges() {
+	if c.deterministic {
+		sortEdges(c.mst.edges)
+	} else {
+		sort.Sort(c.mst.edges)
+	}
+}
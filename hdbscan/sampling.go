@@ -18,10 +18,50 @@ import (
 	"errors"
 	"log"
 	"math"
+	"sort"
 )
 
+// sample reduces the data to the first `sampleBound` points or,
+// with random sampling, to `sampleBound` points drawn with the random
+// generator of the clustering. The points keep their order.
+// A Matrix is never sampled, its rows are used as they are.
 func (c *Clustering) sample() {
-	return
+	if !c.subSample && !c.randomSample {
+		return
+	}
+
+	if c.matrix != nil || c.sampleBound >= len(c.data) {
+		return
+	}
+
+	var indexes []int
+	if c.randomSample {
+		indexes = c.rng().Perm(len(c.data))[:c.sampleBound]
+		sort.Ints(indexes)
+	} else {
+		indexes = make([]int, c.sampleBound)
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+
+	sample := make([][]float64, len(indexes))
+	for i, index := range indexes {
+		sample[i] = c.data[index]
+	}
+
+	if c.verbose {
+		log.Println("sampled data points: ", len(sample), "of", len(c.data))
+	}
+
+	c.data = sample
+	c.sampleIndexes = indexes
+}
+
+// Sample returns for every point of the clustering its index in the
+// data passed to NewClustering, or nil if the data was not sampled.
+func (c *Clustering) Sample() []int {
+	return c.sampleIndexes
 }
 
 // Assign will assign a list of data points to an existing cluster.
@@ -33,7 +73,13 @@ func (c *Clustering) sample() {
 // This method can be useful if a sampling was used for the initial clustering
 // and the data points outside of the sample need to be assigned to a cluster
 // as well.
+// A DataSource clustering does not keep its rows in memory
+// and returns ErrSourceRows.
 func (c *Clustering) Assign(data [][]float64) (*Clustering, error) {
+	if c.source != nil {
+		return &Clustering{}, ErrSourceRows
+	}
+
 	if c.verbose {
 		log.Println("assigning data")
 	}
@@ -63,6 +109,7 @@ func (c *Clustering) Assign(data [][]float64) (*Clustering, error) {
 	}
 
 	// assign data
+	buf := make([]float64, c.dim())
 	for i, v := range data {
 		// calculate nearest cluster
 		minDistance := math.MaxFloat64
@@ -70,7 +117,7 @@ func (c *Clustering) Assign(data [][]float64) (*Clustering, error) {
 		for i, cluster := range c.Clusters {
 			if c.nn {
 				for _, p := range cluster.Points {
-					distance := c.distanceFunc(c.data[p], v)
+					distance := c.distanceFunc(c.pointInto(p, buf), v)
 					if distance < minDistance {
 						minDistance = distance
 						nearestClusterIndex = i
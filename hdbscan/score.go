@@ -58,7 +58,7 @@ func (c *Clustering) setNormalizedSizes() {
 	// distro
 	var sizes []float64
 	for _, cluster := range c.Clusters {
-		size := float64(len(cluster.Points))
+		size := float64(cluster.count)
 		sizes = append(sizes, size)
 		cluster.size = size
 	}
@@ -69,13 +69,19 @@ func (c *Clustering) setNormalizedVariances() {
 	var variances []float64
 	for _, cluster := range c.Clusters {
 		// data
-		var clusterData [][]float64
-		for _, pointIndex := range cluster.Points {
-			clusterData = append(clusterData, c.data[pointIndex])
+		var flatData []float64
+		if c.matrix != nil {
+			flatData = c.matrix.gather(cluster.Points)
+		} else {
+			var clusterData [][]float64
+			for _, pointIndex := range cluster.Points {
+				clusterData = append(clusterData, c.data[pointIndex])
+			}
+			// unfold reshape [][]float64 -> []float64 (reshape to list)
+			// ClusterData contains the point coordinates
+			flatData = unfold(clusterData)
 		}
-		// unfold reshape [][]float64 -> []float64 (reshape to list)
-		// ClusterData contains the point coordinates
-		variance := GeneralizedVariance(len(cluster.Points), len(clusterData[0]), unfold(clusterData))
+		variance := GeneralizedVariance(len(cluster.Points), c.dim(), flatData)
 		cluster.variance = isNum(variance)
 		variances = append(variances, cluster.variance)
 	}
@@ -138,18 +144,22 @@ func (c *Clustering) calculateStability(leaf *cluster) {
 		}
 	}
 
-	// Check child cluster
-	// if sum of score of both child cluster is bigger then the score of parent cluster
-	// score Parent cluster: sum score of child cluster
-	for i := 0; i < len(c.Clusters); i++ {
-		if len(c.Clusters[i].children) == 2 {
-			scoreParentClauster := c.Clusters[i].score
-			scoreChild1 := c.Clusters.getClusterByID(c.Clusters[i].children[0]).score
-			scoreChild2 := c.Clusters.getClusterByID(c.Clusters[i].children[1]).score
+	c.Clusters.sumChildScores()
+}
+
+// Check child cluster
+// if sum of score of both child cluster is bigger then the score of parent cluster
+// score Parent cluster: sum score of child cluster
+func (c clusters) sumChildScores() {
+	for i := 0; i < len(c); i++ {
+		if len(c[i].children) == 2 {
+			scoreParentClauster := c[i].score
+			scoreChild1 := c.getClusterByID(c[i].children[0]).score
+			scoreChild2 := c.getClusterByID(c[i].children[1]).score
 
 			scoreSumChild := scoreChild1 + scoreChild2
 			if scoreParentClauster < scoreSumChild {
-				c.Clusters[i].score = scoreSumChild
+				c[i].score = scoreSumChild
 			}
 		}
 	}
@@ -174,7 +184,7 @@ func (cl *cluster) calcClusterStability(c *Clustering, pointsLastFork []int) {
 			if p1Index == p2Index {
 				continue
 			}
-			currentLam := c.lambda[p1Index][p2Index]
+			currentLam := c.lambdaAt(p1Index, p2Index)
 			if math.IsInf(currentLam, 0) {
 				continue
 			}
@@ -213,9 +223,18 @@ func (cl *cluster) calcClusterStability(c *Clustering, pointsLastFork []int) {
 	c.Clusters.getClusterByID(cl.id).score = sum / float64(len(cl.Points))
 }
 
+// lambdaAt returns the lambda value of two points.
+// Without a lambda matrix it is computed from the data.
+func (c *Clustering) lambdaAt(p1, p2 int) float64 {
+	if c.lambda != nil {
+		return c.lambda[p1][p2]
+	}
+	return 1 / c.distance(p1, p2)
+}
+
 func (c *Clustering) leafScore() {
 	for _, cluster := range c.Clusters {
-		cluster.size = float64(len(cluster.Points))
+		cluster.size = float64(cluster.count)
 		cluster.score = 1
 	}
 }
@@ -36,11 +36,15 @@ func (c *Clustering) selectOptimalClustering(score string) {
 		c.selectbyLeaves()
 	}
 
+	if c.epsilon > 0 {
+		c.applyEpsilon()
+	}
+
 	var finalClusters clusters
 	for _, cluster := range c.Clusters {
 		if cluster.delta == 1 {
 			finalClusters = append(finalClusters, cluster)
-			color.Cyan("Selected cluster Id: %s has %s points", fmt.Sprint(cluster.id), fmt.Sprint(len(cluster.Points)))
+			color.Cyan("Selected cluster Id: %s has %s points", fmt.Sprint(cluster.id), fmt.Sprint(cluster.count))
 		}
 	}
 
@@ -52,6 +56,41 @@ func (c *Clustering) selectOptimalClustering(score string) {
 	}
 }
 
+// applyEpsilon replaces every selected cluster that split off
+// from its parent below the epsilon distance by its nearest
+// ancestor that was born at or above epsilon.
+func (c *Clustering) applyEpsilon() {
+	if c.verbose {
+		log.Println("applying epsilon: ", c.epsilon)
+	}
+
+	for _, cluster := range c.Clusters {
+		if cluster.delta != 1 {
+			continue
+		}
+
+		ancestor := cluster
+		for ancestor.parent != nil {
+			parent := c.Clusters.getClusterByID(*ancestor.parent)
+			// the distance at which the parent link formed
+			// is the distance at which the ancestor was born
+			if parent.dist >= c.epsilon {
+				break
+			}
+			ancestor = parent
+		}
+
+		if ancestor == cluster {
+			continue
+		}
+
+		ancestor.delta = 1
+		for _, subCluster := range c.Clusters.subTree(ancestor.id) {
+			subCluster.delta = 0
+		}
+	}
+}
+
 func (c *Clustering) selectbyLeaves() {
 
 	if c.verbose {
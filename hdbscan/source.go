@@ -0,0 +1,187 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// DataSource provides the data points of a clustering
+// without requiring them to be held in memory at once.
+type DataSource interface {
+	// Len returns the number of data points.
+	Len() int
+	// Dim returns the length of every data point.
+	Dim() int
+	// ReadRows fills dst with the data points starting at index start.
+	// Every row of dst must have a length of `Dim()`.
+	ReadRows(start int, dst [][]float64) error
+}
+
+// The binary source format is a header of two little endian uint64
+// values (number of rows, length of a row) followed by all rows
+// as little endian float64 values.
+const sourceHeaderSize = 16
+
+// MemorySource is a DataSource backed by an in memory slice.
+type MemorySource [][]float64
+
+// Len ...
+func (m MemorySource) Len() int {
+	return len(m)
+}
+
+// Dim ...
+func (m MemorySource) Dim() int {
+	if len(m) == 0 {
+		return 0
+	}
+	return len(m[0])
+}
+
+// ReadRows ...
+func (m MemorySource) ReadRows(start int, dst [][]float64) error {
+	if start < 0 || start+len(dst) > len(m) {
+		return ErrSourceRange
+	}
+	for i := range dst {
+		copy(dst[i], m[start+i])
+	}
+	return nil
+}
+
+// ReaderSource is a DataSource that reads chunks
+// of the binary source format from an io.ReaderAt.
+type ReaderSource struct {
+	r    io.ReaderAt
+	rows int
+	dim  int
+	buf  []byte
+}
+
+// NewReaderSource reads the header of the binary source format
+// and returns a DataSource that reads the rows on demand.
+func NewReaderSource(r io.ReaderAt) (*ReaderSource, error) {
+	header := make([]byte, sourceHeaderSize)
+	_, err := r.ReadAt(header, 0)
+	if err != nil {
+		return nil, ErrSourceHeader
+	}
+
+	rows, dim, err := parseSourceHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReaderSource{r: r, rows: rows, dim: dim}, nil
+}
+
+// Len ...
+func (s *ReaderSource) Len() int {
+	return s.rows
+}
+
+// Dim ...
+func (s *ReaderSource) Dim() int {
+	return s.dim
+}
+
+// ReadRows ...
+func (s *ReaderSource) ReadRows(start int, dst [][]float64) error {
+	if start < 0 || start+len(dst) > s.rows {
+		return ErrSourceRange
+	}
+
+	size := len(dst) * s.dim * 8
+	if cap(s.buf) < size {
+		s.buf = make([]byte, size)
+	}
+	buf := s.buf[:size]
+
+	_, err := s.r.ReadAt(buf, int64(sourceHeaderSize+start*s.dim*8))
+	if err != nil {
+		return err
+	}
+
+	decodeRows(buf, dst, s.dim)
+	return nil
+}
+
+// WriteSource writes data in the binary source format,
+// which can be read by `NewReaderSource` and `OpenMmapSource`.
+func WriteSource(w io.Writer, data [][]float64) error {
+	var dim int
+	if len(data) > 0 {
+		dim = len(data[0])
+	}
+
+	header := make([]byte, sourceHeaderSize)
+	binary.LittleEndian.PutUint64(header[0:8], uint64(len(data)))
+	binary.LittleEndian.PutUint64(header[8:16], uint64(dim))
+	_, err := w.Write(header)
+	if err != nil {
+		return err
+	}
+
+	row := make([]byte, dim*8)
+	for _, r := range data {
+		if len(r) != dim {
+			return ErrRowLength
+		}
+		for j, v := range r {
+			binary.LittleEndian.PutUint64(row[j*8:], math.Float64bits(v))
+		}
+		_, err = w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseSourceHeader(header []byte) (int, int, error) {
+	if len(header) < sourceHeaderSize {
+		return 0, 0, ErrSourceHeader
+	}
+
+	rows := binary.LittleEndian.Uint64(header[0:8])
+	dim := binary.LittleEndian.Uint64(header[8:16])
+	if dim == 0 || rows > math.MaxInt32 || dim > math.MaxInt32 {
+		return 0, 0, ErrSourceHeader
+	}
+
+	return int(rows), int(dim), nil
+}
+
+func decodeRows(buf []byte, dst [][]float64, dim int) {
+	for i := range dst {
+		for j := 0; j < dim; j++ {
+			offset := (i*dim + j) * 8
+			dst[i][j] = math.Float64frombits(binary.LittleEndian.Uint64(buf[offset:]))
+		}
+	}
+}
+
+func newBlock(rows, dim int) [][]float64 {
+	flat := make([]float64, rows*dim)
+	block := make([][]float64, rows)
+	for i := range block {
+		block[i] = flat[i*dim : (i+1)*dim : (i+1)*dim]
+	}
+	return block
+}
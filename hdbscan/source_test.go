@@ -0,0 +1,88 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+import (
+	"bytes"
+	"testing"
+)
+
+// grid returns n points on a grid with four columns and spacing 1.
+func grid(x, y float64, n int) [][]float64 {
+	var data [][]float64
+	for i := 0; i < n; i++ {
+		data = append(data, []float64{x + float64(i%4), y + float64(i/4)})
+	}
+	return data
+}
+
+func TestSourceEqualsMemory(t *testing.T) {
+	var data [][]float64
+	data = append(data, grid(0, 0, 12)...)
+	data = append(data, grid(30, 0, 12)...)
+	data = append(data, grid(0, 30, 12)...)
+
+	var file bytes.Buffer
+	if err := WriteSource(&file, data); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewReaderSource(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		score string
+		mcs   int
+		mst   bool
+	}{
+		{VarianceScore, 3, true},
+		{VarianceScore, 3, false},
+		{VarianceScore, 5, true},
+		{StabilityScore, 5, true},
+	}
+
+	for _, test := range tests {
+		opts := SelectOptions{Score: test.score}
+		memory, err := NewClustering(data, test.mcs, t.TempDir()+"/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := memory.Fit(EuclideanDistance, test.mst); err != nil {
+			t.Fatal(err)
+		}
+		if err := memory.Select(opts); err != nil {
+			t.Fatal(err)
+		}
+		want := memory.Labels()
+
+		for name, source := range map[string]DataSource{"memory source": MemorySource(data), "reader source": reader} {
+			c, err := NewSourceClustering(source, test.mcs, t.TempDir()+"/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.BlockSize(7)
+			if err := c.Fit(EuclideanDistance, true); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Select(opts); err != nil {
+				t.Fatal(err)
+			}
+			if labels := c.Labels(); !samePartition(labels, want) {
+				t.Errorf("%s %s mcs %d mst %v: labels %v, want %v", name, test.score, test.mcs, test.mst, labels, want)
+			}
+		}
+	}
+}
@@ -33,41 +33,62 @@ func GeneralizedVariance(rows, columns int, data []float64) float64 {
 	return math.Abs(det)
 }
 
+// generalizedVarianceOfSums returns the generalized variance of `rows`
+// observations from the sum of the observations and the sum of
+// their outer products, both relative to the same origin.
+func generalizedVarianceOfSums(rows int, sum, products []float64) float64 {
+	columns := len(sum)
+	covMatrix := mat.NewSymDense(columns, nil)
+	for i := 0; i < columns; i++ {
+		for j := i; j < columns; j++ {
+			covariance := (products[i*columns+j] - sum[i]*sum[j]/float64(rows)) / float64(rows-1)
+			covMatrix.SetSym(i, j, covariance)
+		}
+	}
+	det, _ := mat.LogDet(covMatrix)
+	return math.Abs(det)
+}
+
 func (c *Clustering) distanceDistributions() {
+	buf := make([]float64, c.dim())
 	for i, cluster := range c.Clusters {
 		// distance distribution
-		ld := float64(math.MinInt64)
 		var distances []float64
 		for j1, p1 := range cluster.Points {
 			if c.nn {
 				minDistance := math.MaxFloat64
 				for j2, p2 := range cluster.Points {
 					if j1 != j2 {
-						distance := c.distanceFunc(c.data[p1], c.data[p2])
+						distance := c.distance(p1, p2)
 						if distance < minDistance {
 							minDistance = distance
 						}
 					}
 				}
 				distances = append(distances, minDistance)
-
-				// largest NN-distance
-				if minDistance > ld {
-					ld = minDistance
-				}
 			} else {
-				distance := c.distanceFunc(c.data[p1], cluster.Centroid)
+				distance := c.distanceFunc(c.pointInto(p1, buf), cluster.Centroid)
 				distances = append(distances, distance)
-				if distance > ld {
-					ld = distance
-				}
 			}
 		}
-		dd := &distuv.Normal{}
-		dd.Fit(distances, nil)
-		cluster.distanceDistribution = dd
-		cluster.largestDistance = ld
+		cluster.setDistanceDistribution(distances)
 
 		c.Clusters[i] = cluster
 	}
 }
+
+// setDistanceDistribution fits the normal distribution of the
+// distances of the cluster and keeps the largest distance.
+func (cl *cluster) setDistanceDistribution(distances []float64) {
+	ld := float64(math.MinInt64)
+	for _, distance := range distances {
+		if distance > ld {
+			ld = distance
+		}
+	}
+
+	dd := &distuv.Normal{}
+	dd.Fit(distances, nil)
+	cl.distanceDistribution = dd
+	cl.largestDistance = ld
+}
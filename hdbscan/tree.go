@@ -16,6 +16,7 @@ package hdbscan
 
 import (
 	"math"
+	"sort"
 	"sync"
 )
 
@@ -110,3 +111,11 @@ func (e edges) Swap(i, j int) {
 func (e edges) Less(i, j int) bool {
 	return e[j].dist > e[i].dist
 }
+
+// sortEdges sorts edges by distance and breaks ties by the
+// point indexes, so the order does not depend on the input order.
+func sortEdges(e edges) {
+	sort.Slice(e, func(i, j int) bool {
+		return lessEdge(e[i], e[j])
+	})
+}
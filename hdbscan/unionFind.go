@@ -0,0 +1,59 @@
+// Copyright 2020 Humility AI Incorporated, All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hdbscan
+
+type unionFind struct {
+	parent []int
+	rank   []int
+}
+
+func newUnionFind(size int) *unionFind {
+	u := &unionFind{
+		parent: make([]int, size),
+		rank:   make([]int, size),
+	}
+	for i := range u.parent {
+		u.parent[i] = i
+	}
+	return u
+}
+
+func (u *unionFind) find(i int) int {
+	for u.parent[i] != i {
+		u.parent[i] = u.parent[u.parent[i]]
+		i = u.parent[i]
+	}
+	return i
+}
+
+// union joins the sets of i and j and
+// returns false if they already were in the same set.
+func (u *unionFind) union(i, j int) bool {
+	ri, rj := u.find(i), u.find(j)
+	if ri == rj {
+		return false
+	}
+
+	switch {
+	case u.rank[ri] < u.rank[rj]:
+		u.parent[ri] = rj
+	case u.rank[ri] > u.rank[rj]:
+		u.parent[rj] = ri
+	default:
+		u.parent[rj] = ri
+		u.rank[ri]++
+	}
+	return true
+}
@@ -40,6 +40,20 @@ func min(values []float64) (int, float64) {
 	return index, minNum
 }
 
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func unfold(data [][]float64) []float64 {
 	var ud []float64
 	for _, row := range data {
@@ -104,7 +118,7 @@ func (c clusters) Swap(i, j int) {
 
 // Less ...
 func (c clusters) Less(i, j int) bool {
-	return len(c[i].Points) < len(c[j].Points)
+	return c[i].count < c[j].count
 }
 
 func (c clusters) maxID() int {
@@ -119,6 +133,11 @@ func (c clusters) maxID() int {
 }
 
 func (c clusters) getClusterByID(id int) *cluster {
+	// ids of unsorted condensed clusters are their indexes
+	if id >= 0 && id < len(c) && c[id].id == id {
+		return c[id]
+	}
+
 	for _, cluster := range c {
 		if cluster.id == id {
 			return cluster